@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// runDircolors implements `my-ls dircolors [-b|-c|--print-database] [FILE]`,
+// printing shell code that sets LS_COLORS from a dircolors database, or the
+// built-in database itself. It returns the exit status
+func runDircolors(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	shell := ""
+	printDatabase := false
+	var files []string
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-b", "--sh", "--bourne-shell":
+			shell = "sh"
+		case "-c", "--csh", "--c-shell":
+			shell = "csh"
+		case "-p", "--print-database":
+			printDatabase = true
+		case "--":
+			files = append(files, args[i+1:]...)
+			i = len(args)
+		default:
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				fmt.Fprintf(stderr, "Error: dircolors: unrecognized option '%v'\n", arg)
+				return 2
+			}
+			files = append(files, arg)
+		}
+	}
+
+	switch {
+	case len(files) > 1:
+		fmt.Fprintf(stderr, "Error: dircolors: extra operand '%v'\n", files[1])
+		return 2
+	case printDatabase && (shell != "" || len(files) > 0):
+		fmt.Fprintln(stderr, "Error: dircolors: --print-database takes no file and no shell syntax")
+		return 2
+	case printDatabase:
+		fmt.Fprint(stdout, util.DircolorsDatabase)
+		return 0
+	}
+
+	var db io.Reader = strings.NewReader(util.DircolorsDatabase)
+	name := "built-in database"
+	if len(files) == 1 {
+		name = files[0]
+		if name == "-" {
+			db = stdin
+		} else {
+			file, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: dircolors: %v\n", err)
+				return 1
+			}
+			defer file.Close()
+			db = file
+		}
+	}
+
+	term := os.Getenv("TERM")
+	if term == "" {
+		term = "none"
+	}
+	lsColors, err := util.Dircolors(db, term, os.Getenv("COLORTERM"))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: dircolors: %v: %v\n", name, err)
+		return 1
+	}
+
+	if shell == "" {
+		shell = "sh"
+		if strings.HasSuffix(os.Getenv("SHELL"), "csh") {
+			shell = "csh"
+		}
+	}
+	quoted := "'" + strings.ReplaceAll(lsColors, "'", `'\''`) + "'"
+	if shell == "csh" {
+		fmt.Fprintf(stdout, "setenv LS_COLORS %s\n", quoted)
+	} else {
+		fmt.Fprintf(stdout, "LS_COLORS=%s;\nexport LS_COLORS\n", quoted)
+	}
+	return 0
+}
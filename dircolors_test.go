@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+func runDircolorsTest(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runDircolors(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunDircolors(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("COLORTERM", "")
+	t.Setenv("SHELL", "/bin/bash")
+
+	db := filepath.Join(t.TempDir(), "dircolors")
+	os.WriteFile(db, []byte("DIR 01;33\n*it's 35\n"), 0o644)
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  string
+	}{
+		{name: "sh", args: []string{"-b", db}, want: "LS_COLORS='di=01;33:*it'\\''s=35:';\nexport LS_COLORS\n"},
+		{name: "csh", args: []string{"--csh", db}, want: "setenv LS_COLORS 'di=01;33:*it'\\''s=35:'\n"},
+		{name: "last syntax wins", args: []string{"-c", "-b", db}, want: "LS_COLORS='di=01;33:*it'\\''s=35:';\nexport LS_COLORS\n"},
+		{name: "stdin", args: []string{"-b", "-"}, stdin: "EXEC 32\n", want: "LS_COLORS='ex=32:';\nexport LS_COLORS\n"},
+		{name: "print database", args: []string{"--print-database"}, want: util.DircolorsDatabase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runDircolorsTest(tt.args, tt.stdin)
+			if code != 0 || stdout != tt.want {
+				t.Errorf("dircolors %v = %d, %q (stderr %q); want 0, %q", tt.args, code, stdout, stderr, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDircolors_DefaultShell(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("SHELL", "/bin/tcsh")
+
+	_, stdout, _ := runDircolorsTest(nil, "")
+	if !strings.HasPrefix(stdout, "setenv LS_COLORS '") || !strings.Contains(stdout, "*.png=01;35:") {
+		t.Errorf("dircolors under tcsh = %q, want csh syntax for the built-in database", stdout)
+	}
+}
+
+func TestRunDircolors_Errors(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	bad := filepath.Join(t.TempDir(), "bad")
+	os.WriteFile(bad, []byte("NOPE 1\n"), 0o644)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "unknown option", args: []string{"-z"}, code: 2},
+		{name: "two files", args: []string{"a", "b"}, code: 2},
+		{name: "print database with a shell", args: []string{"-p", "-b"}, code: 2},
+		{name: "print database with a file", args: []string{"-p", bad}, code: 2},
+		{name: "missing file", args: []string{"/does/not/exist"}, code: 1},
+		{name: "bad database", args: []string{bad}, code: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runDircolorsTest(tt.args, "")
+			if code != tt.code || stdout != "" || stderr == "" {
+				t.Errorf("dircolors %v = %d, %q, %q; want %d and an error", tt.args, code, stdout, stderr, tt.code)
+			}
+		})
+	}
+}
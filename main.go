@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/jesee-kuya/my-ls/print"
 	"github.com/jesee-kuya/my-ls/util"
@@ -12,11 +14,30 @@ func parseArgs(args []string) (util.Flags, []string) {
 	flags := util.Flags{}
 	var paths []string
 
-	for _, arg := range args {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			// Everything after -- is a path
+			paths = append(paths, args[i+1:]...)
+			break
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			consumed, err := parseLongFlag(arg[2:], args[i+1:], &flags)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+			if consumed {
+				i++
+			}
+			continue
+		}
+
 		if len(arg) > 0 && arg[0] == '-' {
 			// Parse flags
-			for _, char := range arg[1:] {
-				switch char {
+			for j := 1; j < len(arg); j++ {
+				switch char := arg[j]; char {
 				case 'a':
 					flags.ShowAll = true
 				case 'l':
@@ -27,6 +48,67 @@ func parseArgs(args []string) (util.Flags, []string) {
 					flags.Recursive = true
 				case 't':
 					flags.TimeSort = true
+				case 'h':
+					flags.FileSize = util.HumanReadable
+					flags.BlockSize = util.HumanReadable
+				case 'k':
+					flags.BlockSize = util.Scale{Unit: 1024}
+				case 'i':
+					flags.Inode = true
+				case 's':
+					flags.AllocSize = true
+				case 'n':
+					flags.Longformat = true
+					flags.NumericIDs = true
+				case 'g':
+					flags.Longformat = true
+					flags.NoOwner = true
+				case 'o':
+					flags.Longformat = true
+					flags.NoGroup = true
+				case 'G':
+					flags.NoGroup = true
+				case 'L':
+					flags.Dereference = true
+				case '1':
+					// As in GNU ls, -1 does not cancel a long listing
+					if !flags.Longformat {
+						longOptions["format"].apply(&flags, "single-column")
+					}
+				case 'C':
+					longOptions["format"].apply(&flags, "vertical")
+				case 'x':
+					longOptions["format"].apply(&flags, "across")
+				case 'm':
+					longOptions["format"].apply(&flags, "commas")
+				case 'F':
+					longOptions["classify"].apply(&flags, "")
+				case 'p':
+					longOptions["indicator-style"].apply(&flags, "slash")
+				case 'N':
+					longOptions["literal"].apply(&flags, "")
+				case 'b':
+					longOptions["escape"].apply(&flags, "")
+				case 'Q':
+					longOptions["quote-name"].apply(&flags, "")
+				case 'q':
+					longOptions["hide-control-chars"].apply(&flags, "")
+				case 'w', 'T':
+					// -w and -T take the rest of the argument, or the next one
+					name := "width"
+					if char == 'T' {
+						name = "tabsize"
+					}
+					value := arg[j+1:]
+					if value == "" && i+1 < len(args) {
+						i++
+						value = args[i]
+					}
+					if err := longOptions[name].apply(&flags, value); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(2)
+					}
+					j = len(arg)
 				}
 			}
 		} else {
@@ -47,6 +129,11 @@ func main() {
 	var flags util.Flags
 	var paths []string
 
+	// my-ls dircolors is a subcommand; list a directory of that name as ./dircolors
+	if len(os.Args) > 1 && os.Args[1] == "dircolors" {
+		os.Exit(runDircolors(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	if len(os.Args) > 1 {
 		flags, paths = parseArgs(os.Args[1:])
 	} else {
@@ -160,7 +160,7 @@ func TestParseArgs_EdgeCases(t *testing.T) {
 	})
 
 	t.Run("unknown flag", func(t *testing.T) {
-		flags, paths := parseArgs([]string{"-x"})
+		flags, paths := parseArgs([]string{"-y"})
 		expectedFlags := util.Flags{ShowAll: false, Longformat: false, Reverse: false, Recursive: false, TimeSort: false}
 		expectedPaths := []string{"."}
 
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/my-ls/query"
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// longOption describes a --name[=value] command-line option
+type longOption struct {
+	hasValue bool // the option takes a value, either after '=' or as the next argument
+	optional bool // the option may take a value, but only after '='
+	apply    func(flags *util.Flags, value string) error
+}
+
+// filterOption builds a long option that adds a find-style filter
+func filterOption(name string, hasValue bool) longOption {
+	return longOption{
+		hasValue: hasValue,
+		apply: func(flags *util.Flags, value string) error {
+			p, err := util.NewFilter(name, value)
+			if err != nil {
+				return err
+			}
+			flags.Filters = append(flags.Filters, p)
+			return nil
+		},
+	}
+}
+
+// idFilterOption builds the --user or --group filter, keeping the name so
+// that Print can report it if the resolver does not know it
+func idFilterOption(name string) longOption {
+	filter := filterOption(name, true)
+	return longOption{
+		hasValue: true,
+		apply: func(flags *util.Flags, value string) error {
+			if err := filter.apply(flags, value); err != nil {
+				return err
+			}
+			flags.IDNames = append(flags.IDNames, util.IDName{Group: name == "group", Name: value})
+			return nil
+		},
+	}
+}
+
+// longOptions maps long option names to their handlers
+var longOptions = map[string]longOption{
+	"type":       filterOption("type", true),
+	"size":       filterOption("size", true),
+	"newer-than": filterOption("newer-than", true),
+	"older-than": filterOption("older-than", true),
+	"newer":      filterOption("newer", true),
+	"user":       idFilterOption("user"),
+	"group":      idFilterOption("group"),
+	"perm":       filterOption("perm", true),
+	"empty":      filterOption("empty", false),
+	"readable":   filterOption("readable", false),
+	"writable":   filterOption("writable", false),
+	"executable": filterOption("executable", false),
+	"human-readable": {apply: func(flags *util.Flags, _ string) error {
+		flags.FileSize, flags.BlockSize = util.HumanReadable, util.HumanReadable
+		return nil
+	}},
+	"si": {apply: func(flags *util.Flags, _ string) error {
+		flags.FileSize, flags.BlockSize = util.SI, util.SI
+		return nil
+	}},
+	"block-size": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		scale, err := util.ParseBlockSize(value)
+		if err != nil {
+			return err
+		}
+		flags.FileSize, flags.BlockSize = scale, scale
+		return nil
+	}},
+	"kibibytes": {apply: func(flags *util.Flags, _ string) error {
+		flags.BlockSize = util.Scale{Unit: 1024}
+		return nil
+	}},
+	"inode": {apply: func(flags *util.Flags, _ string) error {
+		flags.Inode = true
+		return nil
+	}},
+	"numeric-uid-gid": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.NumericIDs = true, true
+		return nil
+	}},
+	"no-group": {apply: func(flags *util.Flags, _ string) error {
+		flags.NoGroup = true
+		return nil
+	}},
+	"id-resolver": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		switch value {
+		case "nss":
+			flags.Resolver = util.NSSResolver{}
+		case "files":
+			flags.Resolver = util.NewFileResolver("/")
+		default:
+			return fmt.Errorf("invalid argument '%v' for '--id-resolver' (valid: nss, files)", value)
+		}
+		return nil
+	}},
+	"id-root": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		flags.Resolver = util.NewFileResolver(value)
+		return nil
+	}},
+	"color": {optional: true, apply: func(flags *util.Flags, value string) error {
+		mode, err := util.ParseColorMode(value)
+		flags.Color = mode
+		return err
+	}},
+	"color-scale": {optional: true, apply: func(flags *util.Flags, value string) error {
+		scale, err := util.ParseColorScale(value)
+		flags.ColorScale = scale
+		return err
+	}},
+	"icons": {optional: true, apply: func(flags *util.Flags, value string) error {
+		mode, err := util.ParseIconMode(value)
+		flags.Icons = mode
+		return err
+	}},
+	"quoting-style": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		style, err := util.ParseQuotingStyle(value)
+		flags.Quoting = style
+		return err
+	}},
+	"literal": {apply: func(flags *util.Flags, _ string) error {
+		flags.Quoting = util.QuoteLiteral
+		return nil
+	}},
+	"escape": {apply: func(flags *util.Flags, _ string) error {
+		flags.Quoting = util.QuoteEscape
+		return nil
+	}},
+	"quote-name": {apply: func(flags *util.Flags, _ string) error {
+		flags.Quoting = util.QuoteC
+		return nil
+	}},
+	"hide-control-chars": {apply: func(flags *util.Flags, _ string) error {
+		flags.HideControl, flags.ShowControl = true, false
+		return nil
+	}},
+	"show-control-chars": {apply: func(flags *util.Flags, _ string) error {
+		flags.HideControl, flags.ShowControl = false, true
+		return nil
+	}},
+	"zero": {apply: func(flags *util.Flags, _ string) error {
+		flags.Zero = true
+		return nil
+	}},
+	"json": {apply: func(flags *util.Flags, _ string) error {
+		flags.JSON = true
+		return nil
+	}},
+	"format": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		if value == "long" || value == "verbose" {
+			flags.Longformat, flags.Format = true, util.FormatDefault
+			return nil
+		}
+		format, err := util.ParseFormat(value)
+		if err != nil {
+			return err
+		}
+		flags.Longformat, flags.Format = false, format
+		return nil
+	}},
+	"indicator-style": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		style, err := util.ParseIndicatorStyle(value)
+		flags.Indicator, flags.ClassifyAuto = style, false
+		return err
+	}},
+	"classify": {optional: true, apply: func(flags *util.Flags, value string) error {
+		// Takes the same WHEN values as --color
+		mode, err := util.ParseColorMode(value)
+		if err != nil {
+			return fmt.Errorf("invalid argument '%v' for '--classify' (valid: always, never, auto)", value)
+		}
+		flags.Indicator, flags.ClassifyAuto = util.IndicatorNone, mode == util.ColorAuto
+		if mode == util.ColorAlways {
+			flags.Indicator = util.IndicatorClassify
+		}
+		return nil
+	}},
+	"file-type": {apply: func(flags *util.Flags, _ string) error {
+		flags.Indicator, flags.ClassifyAuto = util.IndicatorFileType, false
+		return nil
+	}},
+	"dereference": {apply: func(flags *util.Flags, _ string) error {
+		flags.Dereference = true
+		return nil
+	}},
+	"root": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		info, err := os.Stat(value)
+		if err != nil {
+			return fmt.Errorf("cannot use '%v' as --root: %v", value, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("cannot use '%v' as --root: not a directory", value)
+		}
+		flags.Root = value
+		flags.Resolver = util.NewFileResolver(value)
+		return nil
+	}},
+	"width": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid line width: '%v'", value)
+		}
+		flags.Width, flags.WidthSet = n, true
+		return nil
+	}},
+	"grid-long": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.GridLong = true, true
+		return nil
+	}},
+	"columns": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		columns, err := util.ParseColumns(value)
+		if err != nil {
+			return err
+		}
+		flags.Longformat, flags.Columns = true, columns
+		return nil
+	}},
+	"octal-perms": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.OctalPerms = true, true
+		return nil
+	}},
+	"perms-explain": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.PermsExplain = true, true
+		return nil
+	}},
+	"access": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.Access = true, true
+		return nil
+	}},
+	"xattrs": {apply: func(flags *util.Flags, _ string) error {
+		flags.Longformat, flags.Xattrs = true, true
+		return nil
+	}},
+	"header": {apply: func(flags *util.Flags, _ string) error {
+		flags.Header = true
+		return nil
+	}},
+	"tabsize": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid tab size: '%v'", value)
+		}
+		flags.TabSize = n
+		return nil
+	}},
+	"where": {hasValue: true, apply: func(flags *util.Flags, value string) error {
+		p, err := query.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid --where expression at %v", err)
+		}
+		flags.Filters = append(flags.Filters, p)
+		return nil
+	}},
+}
+
+// parseLongFlag applies a single long option, reading its value from next
+// when it was not given inline. It reports whether next was consumed
+func parseLongFlag(arg string, next []string, flags *util.Flags) (bool, error) {
+	name, value, inline := strings.Cut(arg, "=")
+	opt, ok := longOptions[name]
+	if !ok {
+		return false, fmt.Errorf("unrecognized option '--%v'", name)
+	}
+
+	consumed := false
+	if opt.hasValue && !inline {
+		if len(next) == 0 {
+			return false, fmt.Errorf("option '--%v' requires an argument", name)
+		}
+		value, consumed = next[0], true
+	} else if !opt.hasValue && !opt.optional && inline {
+		return false, fmt.Errorf("option '--%v' doesn't allow an argument", name)
+	}
+
+	return consumed, opt.apply(flags, value)
+}
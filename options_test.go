@@ -0,0 +1,218 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+func TestParseArgs_FilterOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectFilters int
+		expectedPaths []string
+	}{
+		{
+			name:          "inline value",
+			args:          []string{"--type=f,d"},
+			expectFilters: 1,
+			expectedPaths: []string{"."},
+		},
+		{
+			name:          "separate value",
+			args:          []string{"--size", "+10M", "/tmp"},
+			expectFilters: 1,
+			expectedPaths: []string{"/tmp"},
+		},
+		{
+			name:          "several filters with short flags",
+			args:          []string{"-lR", "--empty", "--perm=/o+w", "--newer-than=2d", "/tmp"},
+			expectFilters: 3,
+			expectedPaths: []string{"/tmp"},
+		},
+		{
+			name:          "access filters",
+			args:          []string{"--writable", "--executable"},
+			expectFilters: 2,
+			expectedPaths: []string{"."},
+		},
+		{
+			name:          "double dash ends options",
+			args:          []string{"--empty", "--", "--type=f", "-l"},
+			expectFilters: 1,
+			expectedPaths: []string{"--type=f", "-l"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, paths := parseArgs(tt.args)
+
+			if len(flags.Filters) != tt.expectFilters {
+				t.Errorf("parseArgs(%v) got %d filters, want %d", tt.args, len(flags.Filters), tt.expectFilters)
+			}
+			if !reflect.DeepEqual(paths, tt.expectedPaths) {
+				t.Errorf("parseArgs(%v) paths = %v, want %v", tt.args, paths, tt.expectedPaths)
+			}
+		})
+	}
+}
+
+func TestParseLongFlag_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  string
+		next []string
+	}{
+		{name: "unknown option", arg: "bogus"},
+		{name: "missing value", arg: "type"},
+		{name: "unexpected value", arg: "empty=yes"},
+		{name: "invalid value", arg: "size=huge"},
+		{name: "missing root", arg: "root=/does/not/exist"},
+		{name: "bad width", arg: "width=wide"},
+		{name: "bad color", arg: "color=sometimes"},
+		{name: "bad color scale", arg: "color-scale=heat"},
+		{name: "bad icons", arg: "icons=sometimes"},
+		{name: "bad quoting style", arg: "quoting-style=perl"},
+		{name: "bad indicator style", arg: "indicator-style=all"},
+		{name: "bad classify", arg: "classify=sometimes"},
+		{name: "missing quoting style", arg: "quoting-style"},
+		{name: "bad format", arg: "format=grid"},
+		{name: "bad tab size", arg: "tabsize=-8"},
+		{name: "bad column", arg: "columns=name,colour"},
+		{name: "negative width", arg: "width=-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := util.Flags{}
+			if _, err := parseLongFlag(tt.arg, tt.next, &flags); err == nil {
+				t.Errorf("parseLongFlag(%q) expected an error", tt.arg)
+			}
+		})
+	}
+}
+
+func TestParseArgs_SizeOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		fileSize  util.Scale
+		blockSize util.Scale
+	}{
+		{name: "-h", args: []string{"-lh"}, fileSize: util.HumanReadable, blockSize: util.HumanReadable},
+		{name: "--si", args: []string{"--si"}, fileSize: util.SI, blockSize: util.SI},
+		{name: "-k only affects blocks", args: []string{"-k"}, blockSize: util.Scale{Unit: 1024}},
+		{name: "-h then -k", args: []string{"-h", "-k"}, fileSize: util.HumanReadable, blockSize: util.Scale{Unit: 1024}},
+		{
+			name:      "--block-size",
+			args:      []string{"--block-size=M"},
+			fileSize:  util.Scale{Unit: 1 << 20, Suffix: "M"},
+			blockSize: util.Scale{Unit: 1 << 20, Suffix: "M"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, _ := parseArgs(tt.args)
+			if flags.FileSize != tt.fileSize || flags.BlockSize != tt.blockSize {
+				t.Errorf("parseArgs(%v) sizes = %+v/%+v, want %+v/%+v", tt.args, flags.FileSize, flags.BlockSize, tt.fileSize, tt.blockSize)
+			}
+		})
+	}
+}
+
+func TestParseArgs_ColumnFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want util.Flags
+	}{
+		{args: []string{"-i"}, want: util.Flags{Inode: true}},
+		{args: []string{"-s"}, want: util.Flags{AllocSize: true}},
+		{args: []string{"-n"}, want: util.Flags{Longformat: true, NumericIDs: true}},
+		{args: []string{"-g"}, want: util.Flags{Longformat: true, NoOwner: true}},
+		{args: []string{"-o"}, want: util.Flags{Longformat: true, NoGroup: true}},
+		{args: []string{"-lG"}, want: util.Flags{Longformat: true, NoGroup: true}},
+		{args: []string{"--inode", "--no-group"}, want: util.Flags{Inode: true, NoGroup: true}},
+		{args: []string{"-L"}, want: util.Flags{Dereference: true}},
+		{args: []string{"--dereference"}, want: util.Flags{Dereference: true}},
+		{args: []string{"-w100"}, want: util.Flags{Width: 100, WidthSet: true}},
+		{args: []string{"-lw", "40"}, want: util.Flags{Longformat: true, Width: 40, WidthSet: true}},
+		{args: []string{"--width=0"}, want: util.Flags{WidthSet: true}},
+		{args: []string{"--color"}, want: util.Flags{Color: util.ColorAlways}},
+		{args: []string{"--color=never"}, want: util.Flags{Color: util.ColorNever}},
+		{args: []string{"--color-scale"}, want: util.Flags{ColorScale: util.ColorScale{Age: true, Size: true}}},
+		{args: []string{"--color-scale=size,name"}, want: util.Flags{ColorScale: util.ColorScale{Size: true, Name: true}}},
+		{args: []string{"--icons"}, want: util.Flags{Icons: util.IconsAlways}},
+		{args: []string{"--icons=auto"}, want: util.Flags{Icons: util.IconsAuto}},
+		{args: []string{"--icons", "--icons=never"}, want: util.Flags{}},
+		{args: []string{"--quoting-style=c"}, want: util.Flags{Quoting: util.QuoteC}},
+		{args: []string{"--quoting-style", "shell-always"}, want: util.Flags{Quoting: util.QuoteShellAlways}},
+		{args: []string{"-Q"}, want: util.Flags{Quoting: util.QuoteC}},
+		{args: []string{"-b"}, want: util.Flags{Quoting: util.QuoteEscape}},
+		{args: []string{"-N"}, want: util.Flags{Quoting: util.QuoteLiteral}},
+		{args: []string{"-Qb"}, want: util.Flags{Quoting: util.QuoteEscape}},
+		{args: []string{"-q"}, want: util.Flags{HideControl: true}},
+		{args: []string{"--zero"}, want: util.Flags{Zero: true}},
+		{args: []string{"-F"}, want: util.Flags{Indicator: util.IndicatorClassify}},
+		{args: []string{"-p"}, want: util.Flags{Indicator: util.IndicatorSlash}},
+		{args: []string{"--file-type"}, want: util.Flags{Indicator: util.IndicatorFileType}},
+		{args: []string{"--indicator-style=none", "-F", "--classify=never"}, want: util.Flags{}},
+		{args: []string{"--classify=auto"}, want: util.Flags{ClassifyAuto: true}},
+		{args: []string{"--classify=auto", "-p"}, want: util.Flags{Indicator: util.IndicatorSlash}},
+		{args: []string{"--json"}, want: util.Flags{JSON: true}},
+		{args: []string{"-q", "--show-control-chars"}, want: util.Flags{ShowControl: true}},
+		{args: []string{"--color=always", "--color=auto"}, want: util.Flags{Color: util.ColorAuto}},
+		{args: []string{"-1"}, want: util.Flags{Format: util.FormatSingleColumn}},
+		{args: []string{"-l1"}, want: util.Flags{Longformat: true}},
+		{args: []string{"-l", "-1"}, want: util.Flags{Longformat: true}},
+		{args: []string{"-1l"}, want: util.Flags{Longformat: true, Format: util.FormatSingleColumn}},
+		{args: []string{"-C"}, want: util.Flags{Format: util.FormatVertical}},
+		{args: []string{"--format=horizontal"}, want: util.Flags{Format: util.FormatAcross}},
+		{args: []string{"-lm"}, want: util.Flags{Format: util.FormatCommas}},
+		{args: []string{"-x", "-l"}, want: util.Flags{Longformat: true, Format: util.FormatAcross}},
+		{args: []string{"-x", "--format=verbose"}, want: util.Flags{Longformat: true}},
+		{args: []string{"-T4"}, want: util.Flags{TabSize: 4}},
+		{args: []string{"--grid-long"}, want: util.Flags{Longformat: true, GridLong: true}},
+		{args: []string{"--octal-perms", "--perms-explain"}, want: util.Flags{Longformat: true, OctalPerms: true, PermsExplain: true}},
+		{args: []string{"--access"}, want: util.Flags{Longformat: true, Access: true}},
+		{args: []string{"--xattrs"}, want: util.Flags{Longformat: true, Xattrs: true}},
+		{args: []string{"--columns=size,name", "--header"}, want: util.Flags{Longformat: true, Columns: []string{"size", "name"}, Header: true}},
+		{args: []string{"-T", "8", "--tabsize=0"}, want: util.Flags{}},
+	}
+
+	for _, tt := range tests {
+		flags, _ := parseArgs(tt.args)
+		if !reflect.DeepEqual(flags, tt.want) {
+			t.Errorf("parseArgs(%v) = %+v, want %+v", tt.args, flags, tt.want)
+		}
+	}
+}
+
+func TestParseArgs_Root(t *testing.T) {
+	root := t.TempDir()
+	flags, _ := parseArgs([]string{"--root", root, "-L"})
+	if flags.Root != root || !flags.Dereference {
+		t.Errorf("parseArgs(--root %v -L) = %+v", root, flags)
+	}
+	if r, ok := flags.Resolver.(*util.FileResolver); !ok || r.Root != root {
+		t.Errorf("--root resolver = %#v, want a FileResolver under %v", flags.Resolver, root)
+	}
+}
+
+func TestParseArgs_BareColorKeepsPath(t *testing.T) {
+	flags, paths := parseArgs([]string{"--color", "never"})
+	if flags.Color != util.ColorAlways || !reflect.DeepEqual(paths, []string{"never"}) {
+		t.Errorf("parseArgs(--color never) = %+v, %v; want always and the path never", flags, paths)
+	}
+}
+
+func TestParseArgs_IDNames(t *testing.T) {
+	flags, _ := parseArgs([]string{"--user=alice", "--group", "7"})
+	want := []util.IDName{{Name: "alice"}, {Group: true, Name: "7"}}
+	if len(flags.Filters) != 2 || !reflect.DeepEqual(flags.IDNames, want) {
+		t.Errorf("parseArgs() = %d filters, names %v; want 2 and %v", len(flags.Filters), flags.IDNames, want)
+	}
+}
@@ -0,0 +1,172 @@
+package print
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// jsonEntry is one line of --json output. JSON strings must be UTF-8, so
+// a name that is not also comes as its exact bytes, base64-encoded, in
+// the matching _bytes field
+type jsonEntry struct {
+	Dir         string // the listed directory, "" for file operands
+	DirBytes    []byte
+	Name        string
+	NameBytes   []byte
+	Type        string
+	Mode        string // permission bits in octal
+	Size        int64
+	MTime       string
+	Target      string // for symlinks
+	TargetBytes []byte
+}
+
+// String renders the entry as a single-line JSON object, leaving out the
+// _bytes fields and the target when they are empty
+func (e jsonEntry) String() string {
+	var b strings.Builder
+	b.WriteString(`{"dir":` + jsonString(e.Dir))
+	if len(e.DirBytes) > 0 {
+		b.WriteString(`,"dir_bytes":"` + base64(e.DirBytes) + `"`)
+	}
+	b.WriteString(`,"name":` + jsonString(e.Name))
+	if len(e.NameBytes) > 0 {
+		b.WriteString(`,"name_bytes":"` + base64(e.NameBytes) + `"`)
+	}
+	b.WriteString(`,"type":` + jsonString(e.Type))
+	b.WriteString(`,"mode":` + jsonString(e.Mode))
+	b.WriteString(`,"size":` + strconv.FormatInt(e.Size, 10))
+	b.WriteString(`,"mtime":` + jsonString(e.MTime))
+	if e.Target != "" {
+		b.WriteString(`,"target":` + jsonString(e.Target))
+	}
+	if len(e.TargetBytes) > 0 {
+		b.WriteString(`,"target_bytes":"` + base64(e.TargetBytes) + `"`)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// jsonString quotes s as a JSON string. Ranging over s turns bytes that
+// are not UTF-8 into U+FFFD, and U+2028 and U+2029 are escaped since JavaScript reads them as
+// line breaks
+func jsonString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20, r == '\u2028', r == '\u2029':
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// base64 encodes data with the standard, padded alphabet
+func base64(data []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i += 3 {
+		var n uint32
+		chunk := data[i:min(i+3, len(data))]
+		for j, c := range chunk {
+			n |= uint32(c) << (16 - 8*j)
+		}
+		for j := 0; j < 4; j++ {
+			if j > len(chunk) {
+				b.WriteByte('=')
+			} else {
+				b.WriteByte(base64Alphabet[n>>(18-6*j)&0x3f])
+			}
+		}
+	}
+	return b.String()
+}
+
+// jsonTypes name the kinds of file, by the letters --type uses
+var jsonTypes = map[byte]string{
+	'f': "file",
+	'd': "directory",
+	'l': "symlink",
+	'p': "fifo",
+	's': "socket",
+	'b': "block",
+	'c': "char",
+}
+
+// printJSON writes each listed entry as a JSON object on a line of its own
+func printJSON(w io.Writer, paths, roots []string, flags util.Flags) {
+	for _, dirPath := range paths {
+		readPath := util.HostPath(dirPath, flags.Root)
+		info, err := util.IsValidDir(readPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		if !info.IsDir() {
+			entry := util.Entry{FileInfo: info, Path: readPath}
+			if util.KeepFile(entry, flags) {
+				fmt.Fprintln(w, newJSONEntry("", dirPath, entry))
+			}
+			continue
+		}
+
+		dirFlags := flags
+		dirFlags.Depth = dirDepth(roots, dirPath)
+		entries, err := util.ListEntries(readPath, dirFlags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading directory: %v\n", err)
+			continue
+		}
+		for _, entry := range entries {
+			fmt.Fprintln(w, newJSONEntry(dirPath, entry.Name(), entry))
+		}
+	}
+}
+
+func newJSONEntry(dir, name string, entry util.Entry) jsonEntry {
+	mode := entry.Mode()
+	e := jsonEntry{
+		Dir:       dir,
+		DirBytes:  rawBytes(dir),
+		Name:      name,
+		NameBytes: rawBytes(name),
+		Type:      jsonTypes[util.TypeLetter(mode)],
+		Mode:      fmt.Sprintf("%04o", util.PermBits(mode)),
+		Size:      entry.Size(),
+		MTime:     entry.ModTime().Format(time.RFC3339Nano),
+	}
+	if mode&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(entry.Path); err == nil {
+			e.Target, e.TargetBytes = target, rawBytes(target)
+		}
+	}
+	return e
+}
+
+// rawBytes returns s as bytes when it is not valid UTF-8, and nil otherwise
+func rawBytes(s string) []byte {
+	if util.ValidUTF8(s) {
+		return nil
+	}
+	return []byte(s)
+}
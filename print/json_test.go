@@ -0,0 +1,121 @@
+package print
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+func TestPrintJSON(t *testing.T) {
+	dir := t.TempDir()
+	raw := "caf\xe9.txt" // Latin-1, not UTF-8
+	if err := os.WriteFile(filepath.Join(dir, raw), []byte("hello"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+	os.Symlink(raw, filepath.Join(dir, "link"))
+
+	var buf bytes.Buffer
+	printJSON(&buf, []string{dir}, []string{dir}, util.Flags{})
+
+	link, err := os.Lstat(filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := os.Lstat(filepath.Join(dir, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := mtimes.ReplaceAllString(buf.String(), `"mtime":"-"`)
+	want := `{"dir":"` + dir + `","name":"caf�.txt","name_bytes":"Y2Fm6S50eHQ=","type":"file","mode":"0640","size":5,"mtime":"-"}
+{"dir":"` + dir + `","name":"link","type":"symlink","mode":"` + fmt.Sprintf("%04o", util.PermBits(link.Mode())) + `","size":` + strconv.Itoa(len(raw)) + `,"mtime":"-","target":"caf�.txt","target_bytes":"Y2Fm6S50eHQ="}
+{"dir":"` + dir + `","name":"sub","type":"directory","mode":"0755","size":` + strconv.FormatInt(sub.Size(), 10) + `,"mtime":"-"}
+`
+	if got != want {
+		t.Errorf("printJSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// mtimes matches the mtime field, which varies from run to run
+var mtimes = regexp.MustCompile(`"mtime":"[^"]*"`)
+
+func TestPrint_JSONFileOperand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, nil, 0o644)
+
+	output := captureOutput(func() {
+		Print([]string{path}, util.Flags{JSON: true})
+	})
+
+	want := `{"dir":"","name":"` + path + `","type":"file","mode":"0644","size":0,"mtime":"-"}` + "\n"
+	if got := mtimes.ReplaceAllString(output, `"mtime":"-"`); got != want {
+		t.Errorf("file operand entry = %s, want the path as the name: %s", got, want)
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi" \ bye`, `"say \"hi\" \\ bye"`},
+		{"a\nb\tc\rd", `"a\nb\tc\rd"`},
+		{"\x00\x1b\x7f", `"\u0000\u001b` + "\x7f" + `"`},
+		{"<&>", `"<&>"`},
+		{"日本\u2028", `"日本\u2028"`},
+		{"caf\xe9", "\"caf\ufffd\""},
+	}
+	for _, tt := range tests {
+		if got := jsonString(tt.s); got != tt.want {
+			t.Errorf("jsonString(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestBase64(t *testing.T) {
+	tests := []struct {
+		data, want string
+	}{
+		{"", ""},
+		{"f", "Zg=="},
+		{"fo", "Zm8="},
+		{"foo", "Zm9v"},
+		{"foob", "Zm9vYg=="},
+		{"caf\xe9.txt", "Y2Fm6S50eHQ="},
+		{"\xff\xfe\xfd", "//79"},
+	}
+	for _, tt := range tests {
+		if got := base64([]byte(tt.data)); got != tt.want {
+			t.Errorf("base64(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestPrint_Zero(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a\nb", "c\xff", "d"} {
+		os.WriteFile(filepath.Join(tempDir, name), nil, 0o644)
+	}
+	os.Mkdir(filepath.Join(tempDir, "sub"), 0o755)
+
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{Zero: true})
+	})
+	if want := "a\nb\x00c\xff\x00d\x00sub\x00"; output != want {
+		t.Errorf("--zero output = %q, want %q", output, want)
+	}
+
+	output = captureOutput(func() {
+		Print([]string{tempDir + "/sub", tempDir + "/d"}, util.Flags{Zero: true})
+	})
+	if want := tempDir + "/d\x00" + tempDir + "/sub:\x00"; output != want {
+		t.Errorf("--zero output with headers = %q, want %q", output, want)
+	}
+}
@@ -0,0 +1,182 @@
+package print
+
+import (
+	"strings"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// columnGap is the space between grid columns
+const columnGap = 2
+
+// grid is a layout of cells in rows and columns
+type grid struct {
+	rows, cols int
+	widths     []int // the widest cell in each column
+	across     bool  // cells fill each row in turn (-x) rather than each column (-C)
+}
+
+// index returns which cell goes at row, col
+func (g grid) index(row, col int) int {
+	if g.across {
+		return row*g.cols + col
+	}
+	return col*g.rows + row
+}
+
+// newGrid lays out cells of the given widths in cols columns
+func newGrid(widths []int, cols int, across bool) grid {
+	g := grid{rows: (len(widths) + cols - 1) / cols, cols: cols, across: across}
+	g.widths = make([]int, cols)
+	for row := 0; row < g.rows; row++ {
+		for col := 0; col < cols; col++ {
+			if i := g.index(row, col); i < len(widths) {
+				g.widths[col] = max(g.widths[col], widths[i])
+			}
+		}
+	}
+	return g
+}
+
+// lineWidth is how wide the widest line of g is
+func (g grid) lineWidth() int {
+	total := 0
+	for _, w := range g.widths {
+		total += w
+	}
+	return total + columnGap*(g.cols-1)
+}
+
+// fitGrid finds the layout with the fewest rows that fits in width,
+// falling back to one column when nothing does. No more columns fit than
+// there is room for at the narrowest name, which bounds the search however
+// many names there are
+func fitGrid(widths []int, width int, across bool) grid {
+	total, narrowest := 0, widths[0]
+	for _, w := range widths {
+		total += w
+		narrowest = min(narrowest, w)
+	}
+	if total+columnGap*(len(widths)-1) <= width {
+		return newGrid(widths, len(widths), across)
+	}
+
+	best := newGrid(widths, 1, across)
+	maxCols := min(len(widths), (width+columnGap)/(narrowest+columnGap))
+	for cols := 2; cols <= maxCols; cols++ {
+		if (len(widths)+cols-1)/cols >= best.rows {
+			continue
+		}
+		if g := newGrid(widths, cols, across); g.lineWidth() <= width {
+			best = g
+		}
+	}
+	return best
+}
+
+// render writes files out in g, padding each cell that has another after
+// it on its line. With a tabSize, the padding uses tabs as far as it can
+func (g grid) render(files []string, widths []int, tabSize int) string {
+	var b strings.Builder
+	for row := 0; row < g.rows; row++ {
+		if row > 0 {
+			b.WriteString("\n")
+		}
+		pos, start := 0, 0
+		for col := 0; col < g.cols; col++ {
+			i := g.index(row, col)
+			if i >= len(files) {
+				break
+			}
+			b.WriteString(files[i])
+			pos += widths[i]
+			if col < g.cols-1 && g.index(row, col+1) < len(files) {
+				start += g.widths[col] + columnGap
+				b.WriteString(indent(pos, start, tabSize))
+				pos = start
+			}
+		}
+	}
+	return b.String()
+}
+
+// indent returns the padding from column from to column to, as tabs to
+// each tab stop on the way when tabSize is set, and spaces for the rest
+func indent(from, to, tabSize int) string {
+	var b strings.Builder
+	for from < to {
+		if tabSize > 0 && to/tabSize > (from+1)/tabSize {
+			b.WriteByte('\t')
+			from += tabSize - from%tabSize
+		} else {
+			b.WriteByte(' ')
+			from++
+		}
+	}
+	return b.String()
+}
+
+// cellWidths measures files in terminal cells, without colour codes and
+// counting an icon and its space as two
+func cellWidths(files []string) []int {
+	widths := make([]int, len(files))
+	for i, file := range files {
+		widths[i] = util.DisplayWidth(file)
+	}
+	return widths
+}
+
+// formatGrid lays files out in as few rows as fit in width
+func formatGrid(files []string, width, tabSize int, across bool) string {
+	if len(files) == 0 {
+		return ""
+	}
+	widths := cellWidths(files)
+	return fitGrid(widths, width, across).render(files, widths, tabSize)
+}
+
+// formatCommas lists files separated by commas, starting a new line
+// before a name that would not fit on the current one with its comma
+func formatCommas(files []string, width int) string {
+	var b strings.Builder
+	pos := 0
+	for i, file := range files {
+		w := util.DisplayWidth(file)
+		if i > 0 {
+			if pos+w+2 < width {
+				b.WriteString(", ")
+				pos += 2
+			} else {
+				b.WriteString(",\n")
+				pos = 0
+			}
+		}
+		b.WriteString(file)
+		pos += w
+	}
+	return b.String()
+}
+
+// layout is how the entries of a listing are arranged
+type layout struct {
+	format  util.Format
+	width   int    // the line width
+	tabSize int    // pad grid columns with tabs this many columns apart; 0 for spaces only
+	eol     string // what ends each line
+}
+
+// render arranges files, each line of the result ending in eol
+func (l layout) render(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	switch l.format {
+	case util.FormatVertical:
+		return formatGrid(files, l.width, l.tabSize, false) + l.eol
+	case util.FormatAcross:
+		return formatGrid(files, l.width, l.tabSize, true) + l.eol
+	case util.FormatCommas:
+		return formatCommas(files, l.width) + l.eol
+	}
+	return strings.Join(files, l.eol) + l.eol
+}
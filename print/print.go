@@ -2,141 +2,107 @@ package print
 
 import (
 	"fmt"
+	"os"
 	"strings"
-	"syscall"
-	"unsafe"
 
+	"github.com/jesee-kuya/my-ls/theme"
 	"github.com/jesee-kuya/my-ls/util"
 )
 
-// getTerminalWidth returns the terminal width, defaulting to 80 if unable to determine
-func getTerminalWidth() int {
-	type winsize struct {
-		Row    uint16
-		Col    uint16
-		Xpixel uint16
-		Ypixel uint16
-	}
-
-	ws := &winsize{}
-	retCode, _, _ := syscall.Syscall(syscall.SYS_IOCTL,
-		uintptr(syscall.Stdin),
-		uintptr(syscall.TIOCGWINSZ),
-		uintptr(unsafe.Pointer(ws)))
-
-	if int(retCode) == -1 {
-		return 80 // Default width
-	}
-	return int(ws.Col)
-}
-
 // formatInColumns formats a list of files in columns like standard ls
 func formatInColumns(files []string) string {
-	if len(files) == 0 {
-		return ""
-	}
-
-	// Strip ANSI codes to calculate actual display width
-	displayFiles := make([]string, len(files))
-	fileLengths := make([]int, len(files))
-	maxLen := 0
-	for i, file := range files {
-		displayFiles[i] = util.StripANSI(file)
-		fileLengths[i] = len(displayFiles[i])
-		if fileLengths[i] > maxLen {
-			maxLen = fileLengths[i]
-		}
-	}
-
-	termWidth := getTerminalWidth()
-
-	// Try different numbers of columns to find the optimal layout
-	bestCols := 1
-	bestRows := len(files)
-
-	for numCols := 1; numCols <= len(files); numCols++ {
-		numRows := (len(files) + numCols - 1) / numCols
-
-		// Calculate column widths for this layout (column-major ordering)
-		colWidths := make([]int, numCols)
-		for col := 0; col < numCols; col++ {
-			maxColWidth := 0
-			for row := 0; row < numRows; row++ {
-				idx := col*numRows + row // Column-major indexing
-				if idx < len(files) {
-					if fileLengths[idx] > maxColWidth {
-						maxColWidth = fileLengths[idx]
-					}
-				}
-			}
-			colWidths[col] = maxColWidth
-		}
-
-		// Calculate total width needed
-		totalWidth := 0
-		for i, width := range colWidths {
-			totalWidth += width
-			if i < len(colWidths)-1 {
-				totalWidth += 2 // Space between columns
-			}
-		}
-
-		// If this layout fits and uses fewer rows, use it
-		if totalWidth <= termWidth && numRows < bestRows {
-			bestCols = numCols
-			bestRows = numRows
-		}
-	}
+	return formatInColumnsWidth(files, getTerminalWidth())
+}
 
-	// Format using the best layout
-	numRows := (len(files) + bestCols - 1) / bestCols
+// formatInColumnsWidth lays files out in columns, filled top to bottom, in
+// as few rows as fit in termWidth
+func formatInColumnsWidth(files []string, termWidth int) string {
+	return formatGrid(files, termWidth, 0, false)
+}
 
-	// Calculate column widths for the best layout (column-major ordering)
-	colWidths := make([]int, bestCols)
-	for col := 0; col < bestCols; col++ {
-		maxColWidth := 0
-		for row := 0; row < numRows; row++ {
-			idx := col*numRows + row // Column-major indexing
-			if idx < len(files) {
-				if fileLengths[idx] > maxColWidth {
-					maxColWidth = fileLengths[idx]
-				}
-			}
-		}
-		colWidths[col] = maxColWidth
-	}
-
-	var result strings.Builder
-	for row := 0; row < numRows; row++ {
-		for col := 0; col < bestCols; col++ {
-			idx := col*numRows + row // Column-major indexing
-			if idx < len(files) {
-				result.WriteString(files[idx])
-
-				// Add padding if not the last column in the row
-				if col < bestCols-1 {
-					padding := colWidths[col] - fileLengths[idx] + 2
-					if padding < 2 {
-						padding = 2 // Minimum 2 spaces
-					}
-					result.WriteString(strings.Repeat(" ", padding))
-				}
-			}
+// dirDepth returns how far dir is below the operand in roots it was collected from
+func dirDepth(roots []string, dir string) int {
+	depth, matched := 0, -1
+	for _, root := range roots {
+		if dir == root {
+			return 0
 		}
-		if row < numRows-1 {
-			result.WriteString("\n")
+		prefix := strings.TrimSuffix(root, "/") + "/"
+		if strings.HasPrefix(dir, prefix) && len(prefix) > matched {
+			depth = strings.Count(dir[len(prefix):], "/") + 1
+			matched = len(prefix)
 		}
 	}
-
-	return result.String()
+	return depth
 }
 
+// exit ends the run with a status; tests replace it to see the status
+var exit = os.Exit
+
 func Print(paths []string, flags util.Flags) {
 	outErrors := []string{}
 	singleFiles := []string{}
 	dirContents := []string{}
 	content := []any{}
 
+	if flags.Resolver != nil {
+		util.SetResolver(flags.Resolver)
+	}
+	// Names for --user and --group can only be checked once the resolver
+	// is known; a typo must not quietly list nothing
+	if err := util.CheckIDNames(flags.IDNames); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(2)
+	}
+
+	// Like GNU ls, output for a pipe or file is one entry per line, and
+	// uncoloured unless asked for
+	tty := isTerminal(os.Stdout)
+	flags.NoColor = flags.NoColor || !useColor(flags.Color, tty)
+	flags.ShowIcons = useIcons(flags.Icons, tty)
+	var themeFile *theme.File
+	if !flags.NoColor || flags.ShowIcons {
+		themeFile = loadTheme()
+	}
+	if !flags.NoColor {
+		palette := loadPalette()
+		applyTheme(palette, themeFile, tty)
+		util.SetPalette(palette)
+		flags.TrueColor = trueColor()
+	}
+	if flags.ShowIcons {
+		icons := util.DefaultIcons()
+		applyIcons(icons, themeFile)
+		util.SetIcons(icons)
+	}
+	width := outputWidth(flags)
+
+	// On a terminal names are shell-escaped, so that they can be pasted
+	// into commands, and control characters never reach it raw. With
+	// --zero the names are for another program, and go out as they are,
+	// one to a line
+	format, eol := flags.Format, "\n"
+	switch {
+	case flags.Zero:
+		format, eol = util.FormatSingleColumn, "\x00"
+	case format == util.FormatDefault && tty:
+		format = util.FormatVertical
+	case format == util.FormatDefault:
+		format = util.FormatSingleColumn
+	}
+	if flags.Quoting == util.QuoteDefault {
+		flags.Quoting = util.QuoteLiteral
+		if tty && !flags.Zero {
+			flags.Quoting = util.QuoteShellEscape
+		}
+	}
+	flags.HideControl = flags.HideControl || tty && !flags.ShowControl && !flags.Zero
+	if flags.ClassifyAuto && tty {
+		flags.Indicator = util.IndicatorClassify
+	}
+
+	roots := paths
+
 	// Handle recursive listing
 	if flags.Recursive {
 		allPaths, err := util.CollectDirectoriesRecursively(paths, flags)
@@ -147,32 +113,47 @@ func Print(paths []string, flags util.Flags) {
 		}
 	}
 
+	if flags.JSON {
+		for _, err := range outErrors {
+			fmt.Fprint(os.Stderr, err)
+		}
+		printJSON(os.Stdout, paths, roots, flags)
+		return
+	}
+
 	multipleDirs := false
 	if len(paths) > 1 || flags.Recursive {
 		multipleDirs = true
 	}
 
 	for _, dirPath := range paths {
-		info, err := util.IsValidDir(dirPath)
+		// Under --root, links along the path are followed inside the root
+		readPath := util.HostPath(dirPath, flags.Root)
+		info, err := util.IsValidDir(readPath)
 		if err != nil {
 			outErrors = append(outErrors, fmt.Sprintf("Error: %v\n", err.Error()))
 			continue
 		}
 
 		if !info.IsDir() {
-			singleFiles = append(singleFiles, dirPath)
+			if util.KeepFile(util.Entry{FileInfo: info, Path: readPath}, flags) {
+				singleFiles = append(singleFiles, util.Quote(dirPath, flags.Quoting, flags.HideControl)+util.TypeIndicator(info.Mode(), flags.Indicator))
+			}
 			continue
 		}
 		var files []string
 
+		dirFlags := flags
+		dirFlags.Depth = dirDepth(roots, dirPath)
+
 		if flags.Longformat {
-			files, err = util.ReadDirNamesLong(dirPath, flags)
+			files, err = util.ReadDirNamesLong(readPath, dirFlags)
 			if err != nil {
 				outErrors = append(outErrors, fmt.Sprintf("Error reading directory: %v\n", err.Error()))
 				continue
 			}
 		} else {
-			files, err = util.ReadDirNames(dirPath, flags)
+			files, err = util.ReadDirNames(readPath, dirFlags)
 			if err != nil {
 				outErrors = append(outErrors, fmt.Sprintf("Error reading directory: %v\n", err.Error()))
 				continue
@@ -180,7 +161,7 @@ func Print(paths []string, flags util.Flags) {
 		}
 
 		if multipleDirs {
-			dirContents = append(dirContents, fmt.Sprintf("%v:", dirPath))
+			dirContents = append(dirContents, util.Quote(dirPath, flags.Quoting, flags.HideControl)+":")
 		}
 
 		dirContents = append(dirContents, files...)
@@ -191,22 +172,18 @@ func Print(paths []string, flags util.Flags) {
 		fmt.Println(err)
 	}
 
-	for i, file := range singleFiles {
-		if flags.Longformat {
-			fmt.Println(file)
-			continue
+	if flags.Longformat {
+		format = util.FormatSingleColumn
+		if flags.GridLong && !flags.Zero {
+			format = util.FormatVertical
 		}
-
-		if i == len(singleFiles)-1 {
-			fmt.Print(file + "\n")
-			continue
-		}
-		fmt.Print(file + "  ")
 	}
+	lay := layout{format: format, width: width, tabSize: flags.TabSize, eol: eol}
+	fmt.Print(lay.render(singleFiles))
 
 	for i, c := range content {
 		if i != 0 {
-			fmt.Println()
+			fmt.Print(eol)
 		}
 
 		lines := c.([]string)
@@ -216,22 +193,15 @@ func Print(paths []string, flags util.Flags) {
 
 		// Print directory header (if present)
 		if len(lines) > 0 && len(lines[0]) > 0 && lines[0][len(lines[0])-1] == ':' {
-			fmt.Println(lines[0])
+			fmt.Print(lines[0] + eol)
 			lines = lines[1:] // Skip the header for content printing
 		}
 
-		// Print the directory contents
-		if flags.Longformat {
-			for _, line := range lines {
-				fmt.Println(line)
-			}
-		} else {
-			// Use column formatting for short format
-			formatted := formatInColumns(lines)
-			fmt.Print(formatted)
-			if len(lines) > 0 {
-				fmt.Println() // Add newline after the formatted output
-			}
+		// The total line of -l and -s goes above the entries
+		if (flags.Longformat || flags.AllocSize) && len(lines) > 0 && strings.HasPrefix(lines[0], "total ") {
+			fmt.Print(lines[0] + eol)
+			lines = lines[1:]
 		}
+		fmt.Print(lay.render(lines))
 	}
 }
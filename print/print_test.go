@@ -2,7 +2,9 @@ package print
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -283,3 +285,414 @@ func TestPrint_ReverseFlag(t *testing.T) {
 		t.Errorf("Expected different output with reverse flag, but got same output")
 	}
 }
+
+func TestDirDepth(t *testing.T) {
+	tests := []struct {
+		roots []string
+		dir   string
+		want  int
+	}{
+		{roots: []string{"."}, dir: ".", want: 0},
+		{roots: []string{"."}, dir: "./a", want: 1},
+		{roots: []string{"."}, dir: "./a/b", want: 2},
+		{roots: []string{"/"}, dir: "/etc", want: 1},
+		{roots: []string{"src/", "src/pkg"}, dir: "src/pkg/x", want: 1},
+		{roots: []string{"a", "b"}, dir: "b/c/d", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := dirDepth(tt.roots, tt.dir); got != tt.want {
+			t.Errorf("dirDepth(%v, %q) = %d, want %d", tt.roots, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestGetTerminalWidth_Columns(t *testing.T) {
+	t.Setenv("COLUMNS", "132")
+	if width := getTerminalWidth(); width != 132 {
+		t.Errorf("getTerminalWidth() with COLUMNS=132 = %d", width)
+	}
+
+	t.Setenv("COLUMNS", "junk")
+	if width := getTerminalWidth(); width < 80 {
+		t.Errorf("getTerminalWidth() should ignore a bad COLUMNS, got %d", width)
+	}
+}
+
+func TestOutputWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "100")
+
+	tests := []struct {
+		name  string
+		flags util.Flags
+		want  int
+	}{
+		{name: "no -w", flags: util.Flags{}, want: 100},
+		{name: "-w 40", flags: util.Flags{Width: 40, WidthSet: true}, want: 40},
+		{name: "-w 0", flags: util.Flags{WidthSet: true}, want: math.MaxInt},
+	}
+	for _, tt := range tests {
+		if got := outputWidth(tt.flags); got != tt.want {
+			t.Errorf("%s: outputWidth() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatInColumnsWidth(t *testing.T) {
+	files := []string{"alpha", "beta", "gamma", "delta"}
+
+	if got, want := formatInColumnsWidth(files, 12), "alpha  gamma\nbeta   delta"; got != want {
+		t.Errorf("width 12: got %q, want %q", got, want)
+	}
+	if got, want := formatInColumnsWidth(files, 5), "alpha\nbeta\ngamma\ndelta"; got != want {
+		t.Errorf("width 5: got %q, want %q", got, want)
+	}
+	if got, want := formatInColumnsWidth(files, math.MaxInt), "alpha  beta  gamma  delta"; got != want {
+		t.Errorf("unlimited width: got %q, want %q", got, want)
+	}
+}
+
+func TestPrint_NotATerminal(t *testing.T) {
+	t.Setenv("CLICOLOR_FORCE", "")
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+	for _, file := range []string{"a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(tempDir, file), nil, 0o644)
+	}
+
+	// captureOutput writes to a pipe
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{})
+	})
+
+	if output != "a.txt\nb.txt\ndir\n" {
+		t.Errorf("piped output = %q, want one uncoloured name per line", output)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       util.ColorMode
+		tty        bool
+		noColor    string
+		forceColor string
+		want       bool
+	}{
+		{name: "auto on a terminal", mode: util.ColorAuto, tty: true, want: true},
+		{name: "auto into a pipe", mode: util.ColorAuto, want: false},
+		{name: "always into a pipe", mode: util.ColorAlways, want: true},
+		{name: "never on a terminal", mode: util.ColorNever, tty: true, want: false},
+		{name: "NO_COLOR on a terminal", mode: util.ColorAuto, tty: true, noColor: "1", want: false},
+		{name: "NO_COLOR loses to --color=always", mode: util.ColorAlways, noColor: "1", want: true},
+		{name: "CLICOLOR_FORCE into a pipe", mode: util.ColorAuto, forceColor: "1", want: true},
+		{name: "CLICOLOR_FORCE=0", mode: util.ColorAuto, forceColor: "0", want: false},
+		{name: "CLICOLOR_FORCE loses to --color=never", mode: util.ColorNever, forceColor: "1", want: false},
+		{name: "NO_COLOR beats CLICOLOR_FORCE", mode: util.ColorAuto, noColor: "1", forceColor: "1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("CLICOLOR_FORCE", tt.forceColor)
+			if got := useColor(tt.mode, tt.tty); got != tt.want {
+				t.Errorf("useColor(%v, %v) = %v, want %v", tt.mode, tt.tty, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrint_ColorAlways(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("LS_COLORS", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{Color: util.ColorAlways})
+	})
+
+	if output != "\033[01;34mdir\033[0m\n" {
+		t.Errorf("--color=always output = %q, want a coloured name", output)
+	}
+}
+
+func TestPrint_LSColors(t *testing.T) {
+	t.Setenv("LS_COLORS", "di=04:*.md=33")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "notes.md"), nil, 0o644)
+
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{Color: util.ColorAlways})
+	})
+
+	if want := "\033[04mdir\033[0m\n\033[33mnotes.md\033[0m\n"; output != want {
+		t.Errorf("output with LS_COLORS = %q, want %q", output, want)
+	}
+}
+
+func TestLoadPalette_Invalid(t *testing.T) {
+	t.Setenv("LS_COLORS", "not-a-key=1")
+	if loadPalette() == nil {
+		t.Errorf("loadPalette() should fall back to the defaults")
+	}
+}
+
+func TestTrueColor(t *testing.T) {
+	for value, want := range map[string]bool{"truecolor": true, "24bit": true, "": false, "yes": false} {
+		t.Setenv("COLORTERM", value)
+		if got := trueColor(); got != want {
+			t.Errorf("trueColor() with COLORTERM=%q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestFormatInColumnsWidth_Icons(t *testing.T) {
+	// Each icon is three bytes but one cell, so these fit two to a row in 16 columns
+	files := []string{"\uf15b a.txt", "\uf115 dir", "\uf15b b.txt"}
+	want := "\uf15b a.txt  \uf15b b.txt\n\uf115 dir"
+	if got := formatInColumnsWidth(files, 16); got != want {
+		t.Errorf("formatInColumnsWidth() = %q, want %q", got, want)
+	}
+}
+
+func TestUseIcons(t *testing.T) {
+	tests := []struct {
+		mode util.IconMode
+		tty  bool
+		want bool
+	}{
+		{util.IconsNever, true, false},
+		{util.IconsAlways, false, true},
+		{util.IconsAuto, true, true},
+		{util.IconsAuto, false, false},
+	}
+	for _, tt := range tests {
+		if got := useIcons(tt.mode, tt.tty); got != tt.want {
+			t.Errorf("useIcons(%v, %v) = %v, want %v", tt.mode, tt.tty, got, tt.want)
+		}
+	}
+}
+
+func TestFormatInColumnsWidth_Unicode(t *testing.T) {
+	// 日本語 and the ZWJ family are two cells a character or cluster, and the
+	// combining accent none, so every column lines up by cells
+	files := []string{"日本語", "cafe\u0301", "\U0001f468\u200d\U0001f469\u200d\U0001f467", "ab"}
+	want := "日本語  \U0001f468\u200d\U0001f469\u200d\U0001f467\n" +
+		"cafe\u0301    ab"
+	if got := formatInColumnsWidth(files, 12); got != want {
+		t.Errorf("formatInColumnsWidth() = %q, want %q", got, want)
+	}
+}
+
+func TestPrint_QuotingStyle(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "my dir")
+	os.Mkdir(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "a\tb"), nil, 0o644)
+
+	output := captureOutput(func() {
+		Print([]string{dir, tempDir}, util.Flags{Quoting: util.QuoteC})
+	})
+
+	if want := `"` + dir + `":` + "\n" + `"a\tb"` + "\n"; !strings.HasPrefix(output, want) {
+		t.Errorf("output = %q, want it to start with %q", output, want)
+	}
+}
+
+func TestPrint_LiteralWhenPiped(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a b"), nil, 0o644)
+
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{})
+	})
+
+	if output != "a b\n" {
+		t.Errorf("output into a pipe = %q, want the name as it is", output)
+	}
+}
+
+func TestPrint_ClassifyAutoWhenPiped(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+
+	output := captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{ClassifyAuto: true})
+	})
+	if output != "dir\n" {
+		t.Errorf("--classify=auto into a pipe = %q, want no indicator", output)
+	}
+
+	output = captureOutput(func() {
+		Print([]string{tempDir}, util.Flags{Indicator: util.IndicatorClassify})
+	})
+	if output != "dir/\n" {
+		t.Errorf("-F into a pipe = %q, want an indicator", output)
+	}
+}
+
+func TestFormatInColumnsWidth_Indicators(t *testing.T) {
+	// The indicator takes a cell, so columns stay two spaces apart
+	files := []string{"\033[01;34mdir\033[0m/", "a", "b", "c"}
+	want := "dir/  b\na     c"
+	if got := util.StripANSI(formatInColumnsWidth(files, 8)); got != want {
+		t.Errorf("formatInColumnsWidth() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatGrid_Across(t *testing.T) {
+	files := []string{"alpha", "beta", "gamma", "delta", "eps"}
+	if got, want := formatGrid(files, 20, 0, true), "alpha  beta  gamma\ndelta  eps"; got != want {
+		t.Errorf("formatGrid(across) = %q, want %q", got, want)
+	}
+	if got, want := formatGrid(files, 20, 0, false), "alpha  gamma  eps\nbeta   delta"; got != want {
+		t.Errorf("formatGrid(vertical) = %q, want %q", got, want)
+	}
+}
+
+func TestFormatCommas(t *testing.T) {
+	files := []string{"alpha", "beta", "gamma", "delta"}
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{width: 80, want: "alpha, beta, gamma, delta"},
+		{width: 14, want: "alpha, beta,\ngamma, delta"},
+		{width: 1, want: "alpha,\nbeta,\ngamma,\ndelta"},
+	}
+	for _, tt := range tests {
+		if got := formatCommas(files, tt.width); got != tt.want {
+			t.Errorf("formatCommas(width %d) = %q, want %q", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestPrint_Formats(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		os.WriteFile(filepath.Join(tempDir, name), nil, 0o644)
+	}
+
+	tests := []struct {
+		format util.Format
+		want   string
+	}{
+		{format: util.FormatDefault, want: "a\nb\nc\n"},
+		{format: util.FormatVertical, want: "a  b  c\n"},
+		{format: util.FormatAcross, want: "a  b  c\n"},
+		{format: util.FormatCommas, want: "a, b, c\n"},
+	}
+	for _, tt := range tests {
+		output := captureOutput(func() {
+			Print([]string{tempDir}, util.Flags{Format: tt.format})
+		})
+		if output != tt.want {
+			t.Errorf("format %v into a pipe = %q, want %q", tt.format, output, tt.want)
+		}
+	}
+}
+
+func TestFormatGrid_Tabs(t *testing.T) {
+	// Columns start at 0, 11 and 14; a tab reaches each stop on the way
+	// and spaces make up the rest
+	files := []string{"alpha.txt", "b", "c", "delta", "e", "f"}
+	want := "alpha.txt  b  c\ndelta\t   e  f"
+	if got := formatGrid(files, 20, 8, true); got != want {
+		t.Errorf("formatGrid(tabs) = %q, want %q", got, want)
+	}
+	if got := formatGrid(files, 20, 0, true); strings.Contains(got, "\t") {
+		t.Errorf("formatGrid(no tabs) = %q, want spaces only", got)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		from, to, tabSize int
+		want              string
+	}{
+		{from: 3, to: 5, tabSize: 0, want: "  "},
+		{from: 3, to: 8, tabSize: 8, want: "\t"},
+		{from: 3, to: 10, tabSize: 8, want: "\t  "},
+		{from: 7, to: 9, tabSize: 8, want: "  "},
+		{from: 5, to: 20, tabSize: 4, want: "\t\t\t\t"},
+	}
+	for _, tt := range tests {
+		if got := indent(tt.from, tt.to, tt.tabSize); got != tt.want {
+			t.Errorf("indent(%d, %d, %d) = %q, want %q", tt.from, tt.to, tt.tabSize, got, tt.want)
+		}
+	}
+}
+
+func TestFormatGrid_Large(t *testing.T) {
+	// With the search bounded by the narrowest name this is quick; trying
+	// every column count would take minutes
+	files := make([]string, 100000)
+	for i := range files {
+		files[i] = fmt.Sprintf("f%06d", i)
+	}
+	lines := strings.Split(formatGrid(files, 80, 0, false), "\n")
+	// Names are 7 cells, so 9 columns with gaps of 2 fill 79
+	if want := (100000 + 8) / 9; len(lines) != want {
+		t.Errorf("got %d rows, want %d", len(lines), want)
+	}
+}
+
+func TestPrint_GridLong(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a"), nil, 0o644)
+	os.WriteFile(filepath.Join(tempDir, "b"), nil, 0o644)
+
+	tests := []struct {
+		width int
+		rows  int
+	}{
+		{width: 200, rows: 1},
+		{width: 40, rows: 2},
+	}
+	for _, tt := range tests {
+		output := captureOutput(func() {
+			Print([]string{tempDir}, util.Flags{Longformat: true, GridLong: true, Width: tt.width, WidthSet: true})
+		})
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if !strings.HasPrefix(lines[0], "total ") || len(lines) != tt.rows+1 {
+			t.Errorf("--grid-long at width %d = %q, want a total line and %d rows", tt.width, output, tt.rows)
+			continue
+		}
+		if got := strings.Count(output, "-rw-r--r--"); got != 2 {
+			t.Errorf("--grid-long at width %d = %q, want both records", tt.width, output)
+		}
+	}
+}
+
+func TestPrint_UnknownUserExits(t *testing.T) {
+	oldExit, oldStderr := exit, os.Stderr
+	t.Cleanup(func() { exit, os.Stderr = oldExit, oldStderr })
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	// Stand in for os.Exit, stopping Print where the real one would
+	code := -1
+	exit = func(c int) {
+		code = c
+		panic("exit")
+	}
+	func() {
+		defer func() { recover() }()
+		Print([]string{t.TempDir()}, util.Flags{IDNames: []util.IDName{{Name: "nosuchuser-my-ls"}}})
+	}()
+	w.Close()
+	os.Stderr = oldStderr
+
+	var stderr bytes.Buffer
+	io.Copy(&stderr, r)
+	if code != 2 {
+		t.Fatalf("Print() with an unknown user exited with %d, want 2", code)
+	}
+	if want := "Error: unknown user 'nosuchuser-my-ls'"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
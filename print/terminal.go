@@ -0,0 +1,107 @@
+package print
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+		f.Fd(),
+		uintptr(ioctlReadTermios),
+		uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// getTerminalWidth returns the width to lay output out in: $COLUMNS when it
+// holds a positive number, else the width of the terminal on stdout, else 80
+func getTerminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+
+	type winsize struct {
+		Row    uint16
+		Col    uint16
+		Xpixel uint16
+		Ypixel uint16
+	}
+
+	ws := &winsize{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+		os.Stdout.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(ws)))
+
+	if errno != 0 || ws.Col == 0 {
+		return 80 // Default width
+	}
+	return int(ws.Col)
+}
+
+// outputWidth returns the line width for flags, with -w taking precedence
+// over the environment and the terminal. -w 0 means no limit
+func outputWidth(flags util.Flags) int {
+	if !flags.WidthSet {
+		return getTerminalWidth()
+	}
+	if flags.Width == 0 {
+		return math.MaxInt
+	}
+	return flags.Width
+}
+
+// useColor decides whether output is coloured. An explicit --color=always or
+// --color=never wins; otherwise a non-empty NO_COLOR turns colour off,
+// CLICOLOR_FORCE (other than "0") turns it on, and failing both colour
+// follows whether stdout is a terminal
+func useColor(mode util.ColorMode, tty bool) bool {
+	switch mode {
+	case util.ColorAlways:
+		return true
+	case util.ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true
+	}
+	return tty
+}
+
+// useIcons decides whether names get icons: always or never as asked, and
+// with --icons=auto when stdout is a terminal
+func useIcons(mode util.IconMode, tty bool) bool {
+	return mode == util.IconsAlways || mode == util.IconsAuto && tty
+}
+
+// loadPalette returns the colours from LS_COLORS, or the defaults when it is
+// unset. Like GNU ls, an unparsable value is reported and ignored
+func loadPalette() *util.Palette {
+	spec := os.Getenv("LS_COLORS")
+	if spec == "" {
+		return util.DefaultPalette()
+	}
+	p, err := util.ParseLSColors(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring LS_COLORS: %v\n", err)
+		return util.DefaultPalette()
+	}
+	return p
+}
+
+// trueColor reports whether COLORTERM advertises 24-bit colour
+func trueColor() bool {
+	colorterm := os.Getenv("COLORTERM")
+	return colorterm == "truecolor" || colorterm == "24bit"
+}
@@ -0,0 +1,11 @@
+//go:build darwin || freebsd
+
+package print
+
+import "syscall"
+
+// The ioctl requests that fetch and set terminal attributes
+const (
+	ioctlReadTermios  = syscall.TIOCGETA
+	ioctlWriteTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,9 @@
+package print
+
+import "syscall"
+
+// The ioctl requests that fetch and set terminal attributes
+const (
+	ioctlReadTermios  = syscall.TCGETS
+	ioctlWriteTermios = syscall.TCSETS
+)
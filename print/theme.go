@@ -0,0 +1,158 @@
+package print
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/jesee-kuya/my-ls/theme"
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// backgroundTimeout bounds how long to wait for the terminal to report its background
+const backgroundTimeout = 200 * time.Millisecond
+
+// loadTheme reads the user's theme file. It returns nil when there is none
+// or, after reporting the problem, when it cannot be used
+func loadTheme() *theme.File {
+	path := theme.Path()
+	if path == "" {
+		return nil
+	}
+	file, err := theme.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring theme: %v\n", err)
+		return nil
+	}
+	return file
+}
+
+// applyTheme layers the theme file's colours, if it has any, over p. With
+// background = "auto" and both variants defined, a terminal on stdout is
+// asked for its background colour
+func applyTheme(p *util.Palette, file *theme.File, tty bool) {
+	if file == nil || len(file.Themes) == 0 {
+		return
+	}
+	t, ok := file.Selected()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring theme: several themes are defined but none is chosen with theme = \"NAME\"\n")
+		return
+	}
+
+	light := file.Background == "light"
+	if file.Background == "auto" && t.Light != nil && t.Dark != nil {
+		light = backgroundIsLight(tty)
+	}
+	if err := t.Pick(light).Apply(p); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring theme: %v\n", err)
+	}
+}
+
+// applyIcons layers the theme file's icon overrides, if it has any, over s
+func applyIcons(s *util.IconSet, file *theme.File) {
+	if file == nil || file.Icons == nil {
+		return
+	}
+	if err := file.Icons.Apply(s); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring theme icons: %v\n", err)
+	}
+}
+
+// backgroundIsLight guesses whether the terminal has a light background:
+// by asking it, when stdout is one, then from COLORFGBG, and otherwise
+// assuming dark
+func backgroundIsLight(tty bool) bool {
+	if tty {
+		if light, ok := queryBackground(backgroundTimeout); ok {
+			return light
+		}
+	}
+	if _, bg, ok := strings.Cut(os.Getenv("COLORFGBG"), ";"); ok {
+		// rxvt-style "fg;bg" or "fg;default;bg", in the 16 basic colours
+		if i := strings.LastIndexByte(bg, ';'); i >= 0 {
+			bg = bg[i+1:]
+		}
+		if n, err := strconv.Atoi(bg); err == nil {
+			return n == 7 || n >= 9 && n <= 15
+		}
+	}
+	return false
+}
+
+// queryBackground asks the controlling terminal for its background colour
+// with OSC 11, waiting at most timeout for the reply
+func queryBackground(timeout time.Duration) (light, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, false
+	}
+	defer tty.Close()
+
+	// Read the reply unechoed and without waiting for a newline, in reads
+	// that give up after a tenth of a second
+	var saved syscall.Termios
+	if !termios(tty, ioctlReadTermios, &saved) {
+		return false, false
+	}
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if !termios(tty, ioctlWriteTermios, &raw) {
+		return false, false
+	}
+	defer termios(tty, ioctlWriteTermios, &saved)
+
+	if _, err := tty.WriteString("\033]11;?\033\\"); err != nil {
+		return false, false
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		n, _ := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if s := string(reply); strings.HasSuffix(s, "\a") || strings.HasSuffix(s, "\033\\") {
+			break
+		}
+	}
+	return parseBackground(string(reply))
+}
+
+func termios(f *os.File, request uintptr, t *syscall.Termios) bool {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(t)))
+	return errno == 0
+}
+
+// parseBackground reads an OSC 11 reply such as
+// "\033]11;rgb:ffff/ffff/dddd\033\\" and reports whether the colour is light
+func parseBackground(reply string) (light, ok bool) {
+	_, spec, found := strings.Cut(reply, "rgb:")
+	if !found {
+		return false, false
+	}
+	spec = strings.TrimRight(spec, "\a\033\\")
+	parts := strings.Split(spec, "/")
+	if len(parts) != 3 {
+		return false, false
+	}
+
+	var channels [3]float64
+	for i, part := range parts {
+		if len(part) == 0 || len(part) > 4 {
+			return false, false
+		}
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil {
+			return false, false
+		}
+		channels[i] = float64(v) / float64(uint64(1)<<(4*len(part))-1)
+	}
+	luminance := 0.2126*channels[0] + 0.7152*channels[1] + 0.0722*channels[2]
+	return luminance > 0.5, true
+}
@@ -0,0 +1,119 @@
+package print
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+func TestParseBackground(t *testing.T) {
+	tests := []struct {
+		reply string
+		light bool
+		ok    bool
+	}{
+		{reply: "\033]11;rgb:ffff/ffff/ffff\033\\", light: true, ok: true},
+		{reply: "\033]11;rgb:0000/2b2b/3636\a", light: false, ok: true},
+		{reply: "\033]11;rgb:fd/f6/e3\a", light: true, ok: true},
+		{reply: "\033]11;rgb:2/2/2\a", light: false, ok: true},
+		{reply: "", ok: false},
+		{reply: "\033]11;rgb:ffff/ffff\a", ok: false},
+		{reply: "\033]11;rgb:fffff/0/0\a", ok: false},
+		{reply: "\033]11;rgb:zz/00/00\a", ok: false},
+	}
+	for _, tt := range tests {
+		light, ok := parseBackground(tt.reply)
+		if light != tt.light || ok != tt.ok {
+			t.Errorf("parseBackground(%q) = %v, %v; want %v, %v", tt.reply, light, ok, tt.light, tt.ok)
+		}
+	}
+}
+
+func TestBackgroundIsLight_COLORFGBG(t *testing.T) {
+	for value, want := range map[string]bool{
+		"0;15":         true,
+		"0;7":          true,
+		"15;0":         false,
+		"12;default;0": false,
+		"0;default;15": true,
+		"":             false,
+		"junk":         false,
+	} {
+		t.Setenv("COLORFGBG", value)
+		if got := backgroundIsLight(false); got != want {
+			t.Errorf("backgroundIsLight() with COLORFGBG=%q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	os.Mkdir(filepath.Join(config, "my-ls"), 0o755)
+	os.WriteFile(filepath.Join(config, "my-ls", "theme.toml"), []byte(`background = "light"
+
+[themes.paper.light.entries]
+dir = { fg = "blue", underline = true }
+
+[themes.paper.dark.entries]
+dir = { fg = "bright-blue" }
+`), 0o644)
+
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+
+	p := util.DefaultPalette()
+	applyTheme(p, loadTheme(), false)
+	old := util.DefaultPalette()
+	util.SetPalette(p)
+	defer util.SetPalette(old)
+
+	names, err := util.ReadDirNames(tempDir, util.Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\033[04;34mdir\033[0m"; len(names) != 1 || names[0] != want {
+		t.Errorf("names with a light theme = %q, want [%q]", names, want)
+	}
+}
+
+func TestApplyTheme_Invalid(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	os.Mkdir(filepath.Join(config, "my-ls"), 0o755)
+	os.WriteFile(filepath.Join(config, "my-ls", "theme.json"), []byte(`{"themes": {"x": {"dark": {"entries": {"dir": {"fg": "teal"}}}}}}`), 0o644)
+
+	p := util.DefaultPalette()
+	applyTheme(p, loadTheme(), false)
+	if !reflect.DeepEqual(p, util.DefaultPalette()) {
+		t.Errorf("an invalid theme should leave the palette alone")
+	}
+}
+
+func TestApplyIcons(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	os.Mkdir(filepath.Join(config, "my-ls"), 0o755)
+	os.WriteFile(filepath.Join(config, "my-ls", "theme.toml"), []byte(`[icons.types]
+dir = "D"
+`), 0o644)
+
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "dir"), 0o755)
+
+	s := util.DefaultIcons()
+	applyIcons(s, loadTheme())
+	defer util.SetIcons(util.DefaultIcons())
+	util.SetIcons(s)
+
+	names, err := util.ReadDirNames(tempDir, util.Flags{ShowIcons: true, NoColor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "D dir" {
+		t.Errorf("names with icon overrides = %q, want [\"D dir\"]", names)
+	}
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"time"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// valueType is the static type of an expression
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeNum
+	typeStr
+	typeTime
+	typeDur
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeBool:
+		return "boolean"
+	case typeNum:
+		return "number"
+	case typeStr:
+		return "string"
+	case typeTime:
+		return "time"
+	default:
+		return "duration"
+	}
+}
+
+// value holds the result of evaluating a node; only the field matching its type is set
+type value struct {
+	b bool
+	n int64
+	s string
+	t time.Time
+	d time.Duration
+}
+
+// node is a type-checked expression compiled down to a closure
+type node struct {
+	pos  int
+	typ  valueType
+	eval func(e util.Entry) value
+	lit  *token // the string literal this node was built from, if any
+}
+
+// field is an entry attribute that expressions can refer to by name
+type field struct {
+	typ valueType
+	get func(e util.Entry) value
+}
+
+// fields are the entry attributes available to expressions
+var fields = map[string]field{
+	"name":  {typeStr, func(e util.Entry) value { return value{s: e.Name()} }},
+	"path":  {typeStr, func(e util.Entry) value { return value{s: e.Path} }},
+	"ext":   {typeStr, func(e util.Entry) value { return value{s: util.Extension(e.Name())} }},
+	"size":  {typeNum, func(e util.Entry) value { return value{n: e.Size()} }},
+	"mode":  {typeNum, func(e util.Entry) value { return value{n: int64(util.PermBits(e.Mode()))} }},
+	"uid":   {typeNum, func(e util.Entry) value { return value{n: int64(e.Stat().Uid)} }},
+	"gid":   {typeNum, func(e util.Entry) value { return value{n: int64(e.Stat().Gid)} }},
+	"user":  {typeStr, func(e util.Entry) value { return value{s: util.UserName(e.Stat().Uid)} }},
+	"group": {typeStr, func(e util.Entry) value { return value{s: util.GroupName(e.Stat().Gid)} }},
+	"nlink": {typeNum, func(e util.Entry) value { return value{n: int64(e.Stat().Nlink)} }},
+	"mtime": {typeTime, func(e util.Entry) value { return value{t: e.ModTime()} }},
+	"atime": {typeTime, func(e util.Entry) value { return value{t: util.AccessTime(e.Stat())} }},
+	"ctime": {typeTime, func(e util.Entry) value { return value{t: util.ChangeTime(e.Stat())} }},
+	"depth": {typeNum, func(e util.Entry) value { return value{n: int64(e.Depth)} }},
+	"type":  {typeStr, func(e util.Entry) value { return value{s: string(util.TypeLetter(e.Mode()))} }},
+}
+
+// Compile parses a --where expression and returns a predicate that
+// evaluates it against an entry. now is fixed at compile time so every
+// entry is judged against the same instant
+func Compile(src string) (util.Predicate, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, now: time.Now()}
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.pos, "unexpected %s", describe(tok))
+	}
+	if n.typ != typeBool {
+		return nil, errorAt(n.pos, "expression is a %v, not a condition", n.typ)
+	}
+	return func(e util.Entry) bool { return n.eval(e).b }, nil
+}
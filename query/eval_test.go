@@ -0,0 +1,66 @@
+package query
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+func TestCompile_Eval(t *testing.T) {
+	base := t.TempDir()
+
+	write := func(name string, size int, mode os.FileMode, age time.Duration) util.Entry {
+		path := base + "/" + name
+		os.WriteFile(path, make([]byte, size), 0o644)
+		os.Chmod(path, mode)
+		when := time.Now().Add(-age)
+		os.Chtimes(path, when, when)
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatalf("Lstat(%q) error: %v", path, err)
+		}
+		return util.Entry{FileInfo: info, Path: path, Depth: 2}
+	}
+
+	goFile := write("main.go", 5000, 0o644, time.Hour)
+	oldGo := write("old.go", 5000, 0o644, 30*24*time.Hour)
+	shared := write("shared.txt", 10, 0o666, time.Hour)
+	dotfile := write(".bashrc", 10, 0o644, time.Hour)
+
+	tests := []struct {
+		src   string
+		entry util.Entry
+		want  bool
+	}{
+		{`ext == "go" && size > 4k && mtime > now-7d`, goFile, true},
+		{`ext == "go" && size > 4k && mtime > now-7d`, oldGo, false},
+		{`ext == "go" && size > 4k && mtime > now-7d`, shared, false},
+		{`mode & 0o002 != 0`, shared, true},
+		{`mode & 0o002 != 0`, goFile, false},
+		{`mode == 0644`, goFile, true},
+		{`ext == ""`, dotfile, true},
+		{`name =~ "^old\\."`, oldGo, true},
+		{`name !~ "^old"`, oldGo, false},
+		{`type == "f" && depth == 2`, goFile, true},
+		{`mtime < "2000-01-01"`, goFile, false},
+		{`now - mtime > 7d`, oldGo, true},
+		{`!(size <= 4k) || false`, goFile, true},
+		{`size >= 10 && size <= 10 && nlink == 1`, shared, true},
+		{`uid == ` + strconv.Itoa(os.Getuid()), goFile, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src+"/"+tt.entry.Name(), func(t *testing.T) {
+			p, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile(%q) error: %v", tt.src, err)
+			}
+			if got := p(tt.entry); got != tt.want {
+				t.Errorf("Compile(%q)(%s) = %v, want %v", tt.src, tt.entry.Name(), got, tt.want)
+			}
+		})
+	}
+}
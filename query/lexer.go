@@ -0,0 +1,202 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+// token is one lexical element of an expression. pos is the byte offset it starts at
+type token struct {
+	kind tokenKind
+	pos  int
+	text string
+	num  int64         // value of tokNumber
+	dur  time.Duration // value of tokDuration
+}
+
+// Error is a syntax or type error in an expression, at a 1-based column
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// operators lists the operator tokens, longest first so "<=" wins over "<"
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "+", "-", "&"}
+
+// sizeSuffixes are the binary size units a number may carry
+var sizeSuffixes = map[byte]int64{
+	'k': 1 << 10,
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+	'P': 1 << 50,
+}
+
+// durationSuffixes are the time units a number may carry
+var durationSuffixes = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// lex splits an expression into tokens, ending with a tokEOF
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for {
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+			i++
+		}
+		if i >= len(src) {
+			return append(tokens, token{kind: tokEOF, pos: i}), nil
+		}
+
+		start := i
+		c := src[i]
+		switch {
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: i, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: i, text: ")"})
+			i++
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, errorAt(start, "%v", err)
+			}
+			tokens = append(tokens, token{kind: tokString, pos: start, text: s})
+			i += n
+		case isDigit(c):
+			for i < len(src) && (isIdentChar(src[i]) || src[i] == '.') {
+				i++
+			}
+			tok, err := lexNumber(src[start:i])
+			if err != nil {
+				return nil, errorAt(start, "%v", err)
+			}
+			tok.pos = start
+			tokens = append(tokens, tok)
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, pos: start, text: src[start:i]})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorAt(start, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokOp, pos: start, text: op})
+			i += len(op)
+		}
+	}
+}
+
+// lexString reads a quoted string with Go-style escapes and returns its
+// value and the number of bytes consumed
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			if quote == '\'' {
+				return src[1:i], i + 1, nil
+			}
+			s, err := strconv.Unquote(src[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string literal")
+			}
+			return s, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexNumber parses hex, decimal and octal (0755, 0o755) numbers, sizes such
+// as 4k or 1.5M and durations such as 7d or 90m
+func lexNumber(text string) (token, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		n, err := strconv.ParseInt(text[2:], 16, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number %q", text)
+		}
+		return token{kind: tokNumber, text: text, num: n}, nil
+	}
+
+	last := text[len(text)-1]
+	if unit, ok := sizeSuffixes[last]; ok {
+		f, err := strconv.ParseFloat(text[:len(text)-1], 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid size %q", text)
+		}
+		return token{kind: tokNumber, text: text, num: int64(f * float64(unit))}, nil
+	}
+	if unit, ok := durationSuffixes[last]; ok {
+		f, err := strconv.ParseFloat(text[:len(text)-1], 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid duration %q", text)
+		}
+		return token{kind: tokDuration, text: text, dur: time.Duration(f * float64(unit))}, nil
+	}
+
+	base := 10
+	digits := text
+	switch {
+	case strings.HasPrefix(text, "0o") || strings.HasPrefix(text, "0O"):
+		base, digits = 8, text[2:]
+	case len(text) > 1 && text[0] == '0':
+		base, digits = 8, text[1:]
+	}
+	n, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q", text)
+	}
+	return token{kind: tokNumber, text: text, num: n}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
@@ -0,0 +1,95 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLex(t *testing.T) {
+	tokens, err := lex(`ext == "go" && size > 4k && mtime > now-7d`)
+	if err != nil {
+		t.Fatalf("lex() error: %v", err)
+	}
+
+	want := []struct {
+		kind tokenKind
+		text string
+		pos  int
+	}{
+		{tokIdent, "ext", 0},
+		{tokOp, "==", 4},
+		{tokString, "go", 7},
+		{tokOp, "&&", 12},
+		{tokIdent, "size", 15},
+		{tokOp, ">", 20},
+		{tokNumber, "4k", 22},
+		{tokOp, "&&", 25},
+		{tokIdent, "mtime", 28},
+		{tokOp, ">", 34},
+		{tokIdent, "now", 36},
+		{tokOp, "-", 39},
+		{tokDuration, "7d", 40},
+		{tokEOF, "", 42},
+	}
+
+	if len(tokens) != len(want) {
+		t.Fatalf("lex() returned %d tokens, want %d: %+v", len(tokens), len(want), tokens)
+	}
+	for i, w := range want {
+		got := tokens[i]
+		if got.kind != w.kind || got.text != w.text || got.pos != w.pos {
+			t.Errorf("token %d = {%v %q %d}, want {%v %q %d}", i, got.kind, got.text, got.pos, w.kind, w.text, w.pos)
+		}
+	}
+}
+
+func TestLexNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		num  int64
+		dur  time.Duration
+	}{
+		{text: "42", num: 42},
+		{text: "0755", num: 0o755},
+		{text: "0o2", num: 2},
+		{text: "0x1d", num: 0x1d},
+		{text: "4k", num: 4096},
+		{text: "1.5M", num: 3 << 19},
+		{text: "7d", dur: 7 * 24 * time.Hour},
+		{text: "90m", dur: 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		tok, err := lexNumber(tt.text)
+		if err != nil {
+			t.Errorf("lexNumber(%q) error: %v", tt.text, err)
+			continue
+		}
+		if tok.num != tt.num || tok.dur != tt.dur {
+			t.Errorf("lexNumber(%q) = %d/%v, want %d/%v", tt.text, tok.num, tok.dur, tt.num, tt.dur)
+		}
+	}
+}
+
+func TestLex_Errors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{src: `name == "go`, pos: 9},
+		{src: `size > 12q`, pos: 8},
+		{src: `size # 2`, pos: 6},
+	}
+
+	for _, tt := range tests {
+		_, err := lex(tt.src)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("lex(%q) error = %v, want *Error", tt.src, err)
+			continue
+		}
+		if e.Pos != tt.pos {
+			t.Errorf("lex(%q) error at position %d, want %d (%v)", tt.src, e.Pos, tt.pos, e)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// parser is a recursive-descent parser that type-checks as it goes. From
+// lowest to highest precedence the grammar is:
+//
+//	or         = and { "||" and }
+//	and        = comparison { "&&" comparison }
+//	comparison = additive [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~" ) additive ]
+//	additive   = bitand { ( "+" | "-" ) bitand }
+//	bitand     = unary { "&" unary }
+//	unary      = ( "!" | "-" ) unary | primary
+//	primary    = number | duration | string | field | "now" | "true" | "false" | "(" or ")"
+type parser struct {
+	tokens []token
+	i      int
+	now    time.Time
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+// acceptOp consumes the next token if it is one of ops
+func (p *parser) acceptOp(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func describe(tok token) string {
+	if tok.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+func (p *parser) parseExpr() (*node, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := expectBool(op, left, right); err != nil {
+			return nil, err
+		}
+		l, r := left.eval, right.eval
+		left = &node{pos: left.pos, typ: typeBool, eval: func(e util.Entry) value {
+			return value{b: l(e).b || r(e).b}
+		}}
+	}
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if err := expectBool(op, left, right); err != nil {
+			return nil, err
+		}
+		l, r := left.eval, right.eval
+		left = &node{pos: left.pos, typ: typeBool, eval: func(e util.Entry) value {
+			return value{b: l(e).b && r(e).b}
+		}}
+	}
+}
+
+func expectBool(op token, operands ...*node) error {
+	for _, n := range operands {
+		if n.typ != typeBool {
+			return errorAt(n.pos, "%s needs conditions on both sides, got a %v", op.text, n.typ)
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseComparison() (*node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=", "=~", "!~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "=~" || op.text == "!~" {
+		return p.match(op, left, right)
+	}
+	return p.compare(op, left, right)
+}
+
+// match builds a regular expression match; the pattern must be a string literal
+func (p *parser) match(op token, left, right *node) (*node, error) {
+	if left.typ != typeStr {
+		return nil, errorAt(left.pos, "%s needs a string on its left, got a %v", op.text, left.typ)
+	}
+	if right.lit == nil {
+		return nil, errorAt(right.pos, "%s needs a string literal pattern", op.text)
+	}
+	re, err := regexp.Compile(right.lit.text)
+	if err != nil {
+		return nil, errorAt(right.pos, "invalid pattern: %v", err)
+	}
+	l, negate := left.eval, op.text == "!~"
+	return &node{pos: left.pos, typ: typeBool, eval: func(e util.Entry) value {
+		return value{b: re.MatchString(l(e).s) != negate}
+	}}, nil
+}
+
+// compare builds an equality or ordering test. A string literal compared
+// with a time is read as a date such as "2026-01-01" or an age such as "2d"
+func (p *parser) compare(op token, left, right *node) (*node, error) {
+	var err error
+	if left.typ == typeTime && right.lit != nil {
+		right, err = p.timeLiteral(right)
+	} else if right.typ == typeTime && left.lit != nil {
+		left, err = p.timeLiteral(left)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if left.typ != right.typ {
+		return nil, errorAt(op.pos, "cannot compare %v with %v", left.typ, right.typ)
+	}
+	if left.typ == typeBool && op.text != "==" && op.text != "!=" {
+		return nil, errorAt(op.pos, "conditions can only be compared with == and !=")
+	}
+
+	l, r, typ := left.eval, right.eval, left.typ
+	test := map[string]func(int) bool{
+		"==": func(c int) bool { return c == 0 },
+		"!=": func(c int) bool { return c != 0 },
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+	}[op.text]
+	return &node{pos: left.pos, typ: typeBool, eval: func(e util.Entry) value {
+		return value{b: test(compareValues(typ, l(e), r(e)))}
+	}}, nil
+}
+
+func (p *parser) timeLiteral(n *node) (*node, error) {
+	t, err := util.ParseTimeRef(n.lit.text, p.now)
+	if err != nil {
+		return nil, errorAt(n.pos, "invalid time %q", n.lit.text)
+	}
+	return &node{pos: n.pos, typ: typeTime, eval: func(util.Entry) value { return value{t: t} }}, nil
+}
+
+// compareValues orders two values of the same type, returning -1, 0 or 1
+func compareValues(typ valueType, a, b value) int {
+	switch typ {
+	case typeBool:
+		if a.b == b.b {
+			return 0
+		}
+		return 1
+	case typeNum:
+		return compareInts(a.n, b.n)
+	case typeDur:
+		return compareInts(int64(a.d), int64(b.d))
+	case typeTime:
+		return a.t.Compare(b.t)
+	}
+	switch {
+	case a.s < b.s:
+		return -1
+	case a.s > b.s:
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) parseAdditive() (*node, error) {
+	left, err := p.parseBitAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBitAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// arithmetic adds or subtracts numbers, durations and times
+func arithmetic(op token, left, right *node) (*node, error) {
+	l, r := left.eval, right.eval
+	sign := int64(1)
+	if op.text == "-" {
+		sign = -1
+	}
+
+	switch {
+	case left.typ == typeNum && right.typ == typeNum:
+		return &node{pos: left.pos, typ: typeNum, eval: func(e util.Entry) value {
+			return value{n: l(e).n + sign*r(e).n}
+		}}, nil
+	case left.typ == typeDur && right.typ == typeDur:
+		return &node{pos: left.pos, typ: typeDur, eval: func(e util.Entry) value {
+			return value{d: l(e).d + time.Duration(sign)*r(e).d}
+		}}, nil
+	case left.typ == typeTime && right.typ == typeDur:
+		return &node{pos: left.pos, typ: typeTime, eval: func(e util.Entry) value {
+			return value{t: l(e).t.Add(time.Duration(sign) * r(e).d)}
+		}}, nil
+	case left.typ == typeDur && right.typ == typeTime && op.text == "+":
+		return &node{pos: left.pos, typ: typeTime, eval: func(e util.Entry) value {
+			return value{t: r(e).t.Add(l(e).d)}
+		}}, nil
+	case left.typ == typeTime && right.typ == typeTime && op.text == "-":
+		return &node{pos: left.pos, typ: typeDur, eval: func(e util.Entry) value {
+			return value{d: l(e).t.Sub(r(e).t)}
+		}}, nil
+	}
+	return nil, errorAt(op.pos, "cannot apply %s to %v and %v", op.text, left.typ, right.typ)
+}
+
+func (p *parser) parseBitAnd() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != typeNum || right.typ != typeNum {
+			return nil, errorAt(op.pos, "cannot apply & to %v and %v", left.typ, right.typ)
+		}
+		l, r := left.eval, right.eval
+		left = &node{pos: left.pos, typ: typeNum, eval: func(e util.Entry) value {
+			return value{n: l(e).n & r(e).n}
+		}}
+	}
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	op, ok := p.acceptOp("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	o := operand.eval
+
+	switch {
+	case op.text == "!" && operand.typ == typeBool:
+		return &node{pos: op.pos, typ: typeBool, eval: func(e util.Entry) value { return value{b: !o(e).b} }}, nil
+	case op.text == "-" && operand.typ == typeNum:
+		return &node{pos: op.pos, typ: typeNum, eval: func(e util.Entry) value { return value{n: -o(e).n} }}, nil
+	case op.text == "-" && operand.typ == typeDur:
+		return &node{pos: op.pos, typ: typeDur, eval: func(e util.Entry) value { return value{d: -o(e).d} }}, nil
+	}
+	return nil, errorAt(op.pos, "cannot apply %s to a %v", op.text, operand.typ)
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing.pos, "expected ')' to close '(' at position %d, got %s", tok.pos+1, describe(closing))
+		}
+		return n, nil
+	case tokNumber:
+		return &node{pos: tok.pos, typ: typeNum, eval: func(util.Entry) value { return value{n: tok.num} }}, nil
+	case tokDuration:
+		return &node{pos: tok.pos, typ: typeDur, eval: func(util.Entry) value { return value{d: tok.dur} }}, nil
+	case tokString:
+		return &node{pos: tok.pos, typ: typeStr, lit: &tok, eval: func(util.Entry) value { return value{s: tok.text} }}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			b := tok.text == "true"
+			return &node{pos: tok.pos, typ: typeBool, eval: func(util.Entry) value { return value{b: b} }}, nil
+		case "now":
+			now := p.now
+			return &node{pos: tok.pos, typ: typeTime, eval: func(util.Entry) value { return value{t: now} }}, nil
+		}
+		f, ok := fields[tok.text]
+		if !ok {
+			return nil, errorAt(tok.pos, "unknown field %q", tok.text)
+		}
+		return &node{pos: tok.pos, typ: f.typ, eval: f.get}, nil
+	case tokEOF:
+		return nil, errorAt(tok.pos, "unexpected end of expression")
+	}
+	return nil, errorAt(tok.pos, "unexpected %s", describe(tok))
+}
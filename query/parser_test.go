@@ -0,0 +1,38 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		pos  int
+	}{
+		{name: "unknown field", src: `colour == "red"`, pos: 1},
+		{name: "missing operand", src: `size >`, pos: 7},
+		{name: "unclosed paren", src: `(size > 1 && name == "a"`, pos: 25},
+		{name: "trailing token", src: `size > 1 2`, pos: 10},
+		{name: "type mismatch", src: `size == "big"`, pos: 6},
+		{name: "non-boolean result", src: `size + 1`, pos: 1},
+		{name: "and on numbers", src: `size && true`, pos: 1},
+		{name: "bad regexp", src: `name =~ "("`, pos: 9},
+		{name: "regexp needs literal", src: `name =~ user`, pos: 9},
+		{name: "bad date", src: `mtime > "tomorrow"`, pos: 9},
+		{name: "negated string", src: `!name`, pos: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Compile(%q) error = %v, want *Error", tt.src, err)
+			}
+			if e.Pos != tt.pos {
+				t.Errorf("Compile(%q) error at position %d, want %d (%v)", tt.src, e.Pos, tt.pos, e)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package theme
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseJSON reads a JSON theme file into the same nested maps parseTOML
+// builds. Objects become maps, numbers float64 and arrays []any, as
+// encoding/json would decode them
+func parseJSON(r io.Reader) (map[string]any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s := &jsonScanner{src: string(data), line: 1}
+	s.skipSpace()
+	if s.done() || s.peek() != '{' {
+		return nil, errorAt(s.line, "expected a JSON object")
+	}
+	value, err := s.value()
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if !s.done() {
+		return nil, errorAt(s.line, "unexpected %q after the object", s.src[s.pos])
+	}
+	return value.(map[string]any), nil
+}
+
+// jsonScanner reads a whole JSON document, counting lines for errors
+type jsonScanner struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (s *jsonScanner) done() bool {
+	return s.pos >= len(s.src)
+}
+
+func (s *jsonScanner) peek() byte {
+	return s.src[s.pos]
+}
+
+func (s *jsonScanner) skipSpace() {
+	for !s.done() {
+		switch s.peek() {
+		case '\n':
+			s.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		s.pos++
+	}
+}
+
+// accept consumes c, after any white space, if it comes next
+func (s *jsonScanner) accept(c byte) bool {
+	s.skipSpace()
+	if !s.done() && s.peek() == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// value parses an object, array, string, number, boolean or null
+func (s *jsonScanner) value() (any, error) {
+	s.skipSpace()
+	if s.done() {
+		return nil, errorAt(s.line, "unexpected end of JSON")
+	}
+	switch c := s.peek(); {
+	case c == '{':
+		return s.object()
+	case c == '[':
+		return s.array()
+	case c == '"':
+		return s.str()
+	}
+
+	start := s.pos
+	for !s.done() && strings.IndexByte(" \t\r\n,]}", s.peek()) < 0 {
+		s.pos++
+	}
+	word := s.src[start:s.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if word == "" || !isJSONNumber(word) {
+		return nil, errorAt(s.line, "invalid value %q", word)
+	}
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, errorAt(s.line, "invalid number %q", word)
+	}
+	return n, nil
+}
+
+// isJSONNumber rejects the forms ParseFloat takes but JSON does not,
+// such as hex, underscores, Inf and a leading '+' or '.'
+func isJSONNumber(word string) bool {
+	word = strings.TrimPrefix(word, "-")
+	if word == "" || word[0] < '0' || word[0] > '9' {
+		return false
+	}
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if !(c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *jsonScanner) object() (map[string]any, error) {
+	s.pos++
+	t := map[string]any{}
+	if s.accept('}') {
+		return t, nil
+	}
+	for {
+		s.skipSpace()
+		if s.done() || s.peek() != '"' {
+			return nil, errorAt(s.line, "expected a quoted key")
+		}
+		key, err := s.str()
+		if err != nil {
+			return nil, err
+		}
+		if !s.accept(':') {
+			return nil, errorAt(s.line, "expected ':' after %q", key)
+		}
+		value, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := t[key]; exists {
+			return nil, errorAt(s.line, "key %q defined twice", key)
+		}
+		t[key] = value
+		if s.accept('}') {
+			return t, nil
+		}
+		if !s.accept(',') {
+			return nil, errorAt(s.line, "expected ',' or '}' in object")
+		}
+	}
+}
+
+func (s *jsonScanner) array() ([]any, error) {
+	s.pos++
+	a := []any{}
+	if s.accept(']') {
+		return a, nil
+	}
+	for {
+		value, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, value)
+		if s.accept(']') {
+			return a, nil
+		}
+		if !s.accept(',') {
+			return nil, errorAt(s.line, "expected ',' or ']' in array")
+		}
+	}
+}
+
+// str parses a double-quoted string, decoding escapes and surrogate pairs
+func (s *jsonScanner) str() (string, error) {
+	s.pos++
+	var b strings.Builder
+	for !s.done() {
+		c := s.peek()
+		s.pos++
+		switch {
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20:
+			return "", errorAt(s.line, "control character in string")
+		case c == '\\':
+			if s.done() {
+				return "", errorAt(s.line, "unterminated string")
+			}
+			e := s.peek()
+			s.pos++
+			switch e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				r, err := s.hex4()
+				if err != nil {
+					return "", err
+				}
+				if r >= 0xd800 && r < 0xdc00 && strings.HasPrefix(s.src[s.pos:], "\\u") {
+					s.pos += 2
+					low, err := s.hex4()
+					if err != nil {
+						return "", err
+					}
+					if low >= 0xdc00 && low < 0xe000 {
+						r = 0x10000 + (r-0xd800)<<10 + (low - 0xdc00)
+					} else {
+						b.WriteRune(0xfffd)
+						r = low
+					}
+				}
+				b.WriteRune(r)
+			default:
+				return "", errorAt(s.line, "invalid escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errorAt(s.line, "unterminated string")
+}
+
+// hex4 reads the four hex digits of a \u escape
+func (s *jsonScanner) hex4() (rune, error) {
+	if s.pos+4 > len(s.src) {
+		return 0, errorAt(s.line, "short \\u escape")
+	}
+	r, err := strconv.ParseUint(s.src[s.pos:s.pos+4], 16, 32)
+	if err != nil {
+		return 0, errorAt(s.line, "invalid \\u escape")
+	}
+	s.pos += 4
+	return rune(r), nil
+}
@@ -0,0 +1,71 @@
+package theme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	src := `{
+  "theme": "solarized",
+  "count": 31,
+  "neg": -3.5e1,
+  "flag": true,
+  "none": null,
+  "list": [1, "two", []],
+  "escaped": "a\"b\\c\/\u00e9\ud83d\ude00\n",
+  "themes": {"solarized": {"light": {"entries": {"dir": {"fg": "#268bd2", "bold": true}}}}}
+}
+`
+	got, err := parseJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parseJSON: %v", err)
+	}
+	want := map[string]any{
+		"theme":   "solarized",
+		"count":   float64(31),
+		"neg":     float64(-35),
+		"flag":    true,
+		"none":    nil,
+		"list":    []any{float64(1), "two", []any{}},
+		"escaped": "a\"b\\c/é😀\n",
+		"themes": map[string]any{
+			"solarized": map[string]any{
+				"light": map[string]any{
+					"entries": map[string]any{
+						"dir": map[string]any{"fg": "#268bd2", "bold": true},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseJSON() = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseJSON_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{src: "[1]", line: 1},
+		{src: "{\n\"a\": 1\n\"b\": 2}", line: 3},
+		{src: "{\"a\": 1, \"a\": 2}", line: 1},
+		{src: "{\"a\": \"unterminated", line: 1},
+		{src: "{\n\"a\": 0x1f}", line: 2},
+		{src: "{\"a\": tru}", line: 1},
+		{src: "{\"a\": \"\\q\"}", line: 1},
+		{src: "{a: 1}", line: 1},
+		{src: "{\"a\": 1}\n{}", line: 2},
+		{src: "{\"a\": [1 2]}", line: 1},
+	}
+	for _, tt := range tests {
+		_, err := parseJSON(strings.NewReader(tt.src))
+		e, ok := err.(*Error)
+		if !ok || e.Line != tt.line {
+			t.Errorf("parseJSON(%q) error = %v, want one on line %d", tt.src, err, tt.line)
+		}
+	}
+}
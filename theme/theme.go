@@ -0,0 +1,456 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+// Style is how one class of entry or one long-format column is drawn
+type Style struct {
+	FG        string // colour name, 0-255 palette index or #rrggbb
+	BG        string
+	Bold      bool
+	Dim       bool
+	Underline bool
+}
+
+// Variant is one light or dark set of styles
+type Variant struct {
+	Entries    map[string]Style // by class name, e.g. "dir" or "setuid"
+	Columns    map[string]Style // by long-format column, e.g. "size"
+	Extensions map[string]Style // by suffix or glob, e.g. ".tar" or "*README*"
+}
+
+// Theme is a named theme with a light and/or a dark variant
+type Theme struct {
+	Light *Variant
+	Dark  *Variant
+}
+
+// Icons overrides glyphs of the built-in --icons table
+type Icons struct {
+	Names      map[string]string // by exact name, e.g. "Makefile"
+	Extensions map[string]string // by extension, e.g. ".go"
+	Types      map[string]string // by class name, e.g. "dir"
+}
+
+// File is a theme file: the themes it defines, which to use, whether the
+// background is light, dark or should be asked of the terminal, and any
+// icon overrides
+type File struct {
+	Theme      string
+	Background string // "light", "dark" or "auto"
+	Themes     map[string]Theme
+	Icons      *Icons
+}
+
+// entryClasses maps entry class names to LS_COLORS keys
+var entryClasses = map[string]string{
+	"normal":                "no",
+	"file":                  "fi",
+	"dir":                   "di",
+	"link":                  "ln",
+	"orphan":                "or",
+	"missing":               "mi",
+	"pipe":                  "pi",
+	"socket":                "so",
+	"door":                  "do",
+	"block":                 "bd",
+	"char":                  "cd",
+	"exec":                  "ex",
+	"setuid":                "su",
+	"setgid":                "sg",
+	"sticky":                "st",
+	"other_writable":        "ow",
+	"sticky_other_writable": "tw",
+	"capability":            "ca",
+	"multihardlink":         "mh",
+}
+
+// iconClasses are the entry classes that have icons: the kinds of file
+var iconClasses = []string{"normal", "file", "dir", "link", "orphan", "pipe", "socket", "door", "block", "char", "exec"}
+
+// colourNames are the eight basic colours, in SGR order
+var colourNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Path returns where the theme file is looked for: theme.toml, or failing
+// that theme.json, in $XDG_CONFIG_HOME/my-ls or ~/.config/my-ls. It
+// returns "" when neither exists
+func Path() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	for _, name := range []string{"theme.toml", "theme.json"} {
+		path := filepath.Join(dir, "my-ls", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads a theme file, as JSON if its name ends in .json and as TOML otherwise
+func Load(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var doc map[string]any
+	if strings.HasSuffix(path, ".json") {
+		if doc, err = parseJSON(f); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+	} else if doc, err = parseTOML(f); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	file, err := decodeFile(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return file, nil
+}
+
+// decodeFile builds a File from a parsed TOML or JSON document
+func decodeFile(doc map[string]any) (*File, error) {
+	file := &File{Background: "auto", Themes: map[string]Theme{}}
+	for key, value := range doc {
+		switch key {
+		case "theme", "background":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v must be a string", key)
+			}
+			if key == "theme" {
+				file.Theme = s
+			} else if s != "light" && s != "dark" && s != "auto" {
+				return nil, fmt.Errorf("background must be light, dark or auto, not %q", s)
+			} else {
+				file.Background = s
+			}
+		case "themes":
+			themes, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("themes must be a table")
+			}
+			for name, t := range themes {
+				theme, err := decodeTheme(t)
+				if err != nil {
+					return nil, fmt.Errorf("theme %q: %v", name, err)
+				}
+				file.Themes[name] = theme
+			}
+		case "icons":
+			icons, err := decodeIcons(value)
+			if err != nil {
+				return nil, fmt.Errorf("icons: %v", err)
+			}
+			file.Icons = icons
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	if file.Theme != "" {
+		if _, ok := file.Themes[file.Theme]; !ok {
+			return nil, fmt.Errorf("theme %q is not defined", file.Theme)
+		}
+	}
+	return file, nil
+}
+
+func decodeTheme(value any) (Theme, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return Theme{}, fmt.Errorf("must be a table")
+	}
+	var theme Theme
+	for key, v := range table {
+		variant, err := decodeVariant(v)
+		if err != nil {
+			return Theme{}, fmt.Errorf("%v: %v", key, err)
+		}
+		switch key {
+		case "light":
+			theme.Light = variant
+		case "dark":
+			theme.Dark = variant
+		default:
+			return Theme{}, fmt.Errorf("unknown variant %q (valid: light, dark)", key)
+		}
+	}
+	if theme.Light == nil && theme.Dark == nil {
+		return Theme{}, fmt.Errorf("needs a light or a dark variant")
+	}
+	return theme, nil
+}
+
+func decodeVariant(value any) (*Variant, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a table")
+	}
+	variant := &Variant{}
+	for section, v := range table {
+		styles, err := decodeStyles(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", section, err)
+		}
+		switch section {
+		case "entries":
+			for class := range styles {
+				if _, ok := entryClasses[class]; !ok {
+					return nil, fmt.Errorf("entries: unknown class %q", class)
+				}
+			}
+			variant.Entries = styles
+		case "columns":
+			for name := range styles {
+				if !contains(util.Columns, name) {
+					return nil, fmt.Errorf("columns: unknown column %q (valid: %v)", name, strings.Join(util.Columns, ", "))
+				}
+			}
+			variant.Columns = styles
+		case "extensions":
+			variant.Extensions = styles
+		default:
+			return nil, fmt.Errorf("unknown section %q (valid: entries, columns, extensions)", section)
+		}
+	}
+	return variant, nil
+}
+
+func decodeIcons(value any) (*Icons, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a table")
+	}
+	icons := &Icons{}
+	for section, v := range table {
+		glyphs, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%v must be a table", section)
+		}
+		m := map[string]string{}
+		for key, g := range glyphs {
+			glyph, ok := g.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v: %v must be a string", section, key)
+			}
+			m[key] = glyph
+		}
+		switch section {
+		case "names":
+			icons.Names = m
+		case "extensions":
+			icons.Extensions = m
+		case "types":
+			for class := range m {
+				if !contains(iconClasses, class) {
+					return nil, fmt.Errorf("types: unknown class %q (valid: %v)", class, strings.Join(iconClasses, ", "))
+				}
+			}
+			icons.Types = m
+		default:
+			return nil, fmt.Errorf("unknown section %q (valid: names, extensions, types)", section)
+		}
+	}
+	return icons, nil
+}
+
+func decodeStyles(value any) (map[string]Style, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a table")
+	}
+	styles := map[string]Style{}
+	for name, v := range table {
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%v must be a table of style fields", name)
+		}
+		var style Style
+		for field, fv := range fields {
+			var ok bool
+			switch field {
+			case "fg":
+				style.FG, ok = colourValue(fv)
+			case "bg":
+				style.BG, ok = colourValue(fv)
+			case "bold":
+				style.Bold, ok = fv.(bool)
+			case "dim":
+				style.Dim, ok = fv.(bool)
+			case "underline":
+				style.Underline, ok = fv.(bool)
+			default:
+				return nil, fmt.Errorf("%v: unknown style field %q", name, field)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%v: invalid %v %v", name, field, fv)
+			}
+		}
+		if _, err := style.SGR(); err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		styles[name] = style
+	}
+	return styles, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// colourValue accepts a colour as a string or, for palette indexes, a number
+func colourValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64: // JSON numbers
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// SGR returns the style as SGR parameters such as "01;34"
+func (s Style) SGR() (string, error) {
+	var params []string
+	if s.Bold {
+		params = append(params, "01")
+	}
+	if s.Dim {
+		params = append(params, "02")
+	}
+	if s.Underline {
+		params = append(params, "04")
+	}
+	for i, c := range []string{s.FG, s.BG} {
+		if c == "" {
+			continue
+		}
+		param, err := colourParam(c, i == 1)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, param)
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// colourParam turns a colour name such as "blue" or "bright-blue", a
+// palette index or a #rrggbb value into foreground or background parameters
+func colourParam(c string, background bool) (string, error) {
+	base, extended := 30, "38"
+	if background {
+		base, extended = 40, "48"
+	}
+
+	name := strings.TrimPrefix(c, "bright-")
+	for i, n := range colourNames {
+		if n == name {
+			if name != c {
+				return strconv.Itoa(base + 60 + i), nil
+			}
+			return strconv.Itoa(base + i), nil
+		}
+	}
+	if n, err := strconv.Atoi(c); err == nil && n >= 0 && n <= 255 {
+		return fmt.Sprintf("%v;5;%d", extended, n), nil
+	}
+	if len(c) == 7 && c[0] == '#' {
+		if v, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			return fmt.Sprintf("%v;2;%d;%d;%d", extended, v>>16, v>>8&0xff, v&0xff), nil
+		}
+	}
+	return "", fmt.Errorf("invalid colour %q", c)
+}
+
+// Selected returns the theme the file asks for: the one named by its theme
+// key, or its only theme, or the one called "default"
+func (f *File) Selected() (Theme, bool) {
+	if f.Theme != "" {
+		return f.Themes[f.Theme], true
+	}
+	if len(f.Themes) == 1 {
+		for _, t := range f.Themes {
+			return t, true
+		}
+	}
+	t, ok := f.Themes["default"]
+	return t, ok
+}
+
+// Pick returns the variant for a light or dark background, falling back to
+// the theme's only variant
+func (t Theme) Pick(light bool) *Variant {
+	if light && t.Light != nil || t.Dark == nil {
+		return t.Light
+	}
+	return t.Dark
+}
+
+// Apply sets the palette colours the variant defines. Extensions are added
+// in sorted order, so that among overlapping globs the result is stable
+func (v *Variant) Apply(p *util.Palette) error {
+	for class, style := range v.Entries {
+		sgr, _ := style.SGR()
+		if err := p.Set(entryClasses[class], sgr); err != nil {
+			return err
+		}
+	}
+	for name, style := range v.Columns {
+		sgr, _ := style.SGR()
+		if err := p.SetColumn(name, sgr); err != nil {
+			return err
+		}
+	}
+	patterns := make([]string, 0, len(v.Extensions))
+	for pattern := range v.Extensions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		sgr, _ := v.Extensions[pattern].SGR()
+		if err := p.SetPattern(pattern, sgr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply sets the icons the file overrides
+func (i *Icons) Apply(s *util.IconSet) error {
+	for name, glyph := range i.Names {
+		s.SetName(name, glyph)
+	}
+	for ext, glyph := range i.Extensions {
+		s.SetExtension(ext, glyph)
+	}
+	for class, glyph := range i.Types {
+		if err := s.SetType(entryClasses[class], glyph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jesee-kuya/my-ls/util"
+)
+
+const solarizedTOML = `theme = "solarized"
+background = "auto"
+
+[themes.solarized.light.entries]
+dir = { fg = "#268bd2", bold = true }
+setuid = { fg = "white", bg = "red" }
+
+[themes.solarized.light.columns]
+size = { fg = "bright-black", dim = true }
+
+[themes.solarized.light.extensions]
+".tar" = { fg = 160, underline = true }
+
+[themes.solarized.dark.entries]
+dir = { fg = "blue", bold = true }
+
+[themes.plain.dark.entries]
+file = {}
+`
+
+const solarizedJSON = `{
+  "theme": "solarized",
+  "background": "auto",
+  "themes": {
+    "solarized": {
+      "light": {
+        "entries": {"dir": {"fg": "#268bd2", "bold": true}, "setuid": {"fg": "white", "bg": "red"}},
+        "columns": {"size": {"fg": "bright-black", "dim": true}},
+        "extensions": {".tar": {"fg": 160, "underline": true}}
+      },
+      "dark": {"entries": {"dir": {"fg": "blue", "bold": true}}}
+    },
+    "plain": {"dark": {"entries": {"file": {}}}}
+  }
+}`
+
+func writeTheme(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_TOMLAndJSONAgree(t *testing.T) {
+	fromTOML, err := Load(writeTheme(t, "theme.toml", solarizedTOML))
+	if err != nil {
+		t.Fatalf("Load(toml): %v", err)
+	}
+	fromJSON, err := Load(writeTheme(t, "theme.json", solarizedJSON))
+	if err != nil {
+		t.Fatalf("Load(json): %v", err)
+	}
+	if !reflect.DeepEqual(fromTOML, fromJSON) {
+		t.Errorf("TOML and JSON themes differ:\n%+v\n%+v", fromTOML, fromJSON)
+	}
+
+	theme, ok := fromTOML.Selected()
+	if !ok || theme.Light == nil || theme.Dark == nil {
+		t.Fatalf("Selected() = %+v, %v; want solarized with both variants", theme, ok)
+	}
+	if got := theme.Light.Entries["dir"]; got != (Style{FG: "#268bd2", Bold: true}) {
+		t.Errorf("light dir style = %+v", got)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown key", content: "colour = 1\n", want: `unknown key "colour"`},
+		{name: "bad background", content: "background = \"grey\"\n", want: "background must be"},
+		{name: "undefined theme", content: "theme = \"x\"\n", want: `theme "x" is not defined`},
+		{name: "unknown variant", content: "[themes.a.dusk.entries]\n", want: "unknown variant"},
+		{name: "unknown class", content: "[themes.a.dark.entries]\nfolder = {fg = \"blue\"}\n", want: `unknown class "folder"`},
+		{name: "unknown column", content: "[themes.a.dark.columns]\nowner = {fg = \"blue\"}\n", want: `unknown column "owner"`},
+		{name: "bad colour", content: "[themes.a.dark.entries]\ndir = {fg = \"teal\"}\n", want: `invalid colour "teal"`},
+		{name: "bad field", content: "[themes.a.dark.entries]\ndir = {italic = true}\n", want: `unknown style field "italic"`},
+		{name: "unknown icon class", content: "[icons.types]\nsetuid = \"S\"\n", want: `unknown class "setuid"`},
+		{name: "unknown icon section", content: "[icons.globs]\n", want: `unknown section "globs"`},
+		{name: "icon not a string", content: "[icons.names]\nMakefile = 1\n", want: "Makefile must be a string"},
+		{name: "syntax", content: "theme = \n", want: "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeTheme(t, "theme.toml", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleSGR(t *testing.T) {
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{style: Style{}, want: ""},
+		{style: Style{FG: "blue", Bold: true}, want: "01;34"},
+		{style: Style{FG: "bright-red", BG: "white"}, want: "91;47"},
+		{style: Style{BG: "bright-black", Dim: true, Underline: true}, want: "02;04;100"},
+		{style: Style{FG: "208"}, want: "38;5;208"},
+		{style: Style{FG: "#268bd2", BG: "#fdf6e3"}, want: "38;2;38;139;210;48;2;253;246;227"},
+	}
+	for _, tt := range tests {
+		got, err := tt.style.SGR()
+		if err != nil || got != tt.want {
+			t.Errorf("%+v.SGR() = %q, %v; want %q", tt.style, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"256", "#12345", "#gggggg", "bright-", "light-blue"} {
+		if _, err := (Style{FG: bad}).SGR(); err == nil {
+			t.Errorf("Style{FG: %q}.SGR() expected an error", bad)
+		}
+	}
+}
+
+func TestSelectedAndPick(t *testing.T) {
+	light, dark := &Variant{}, &Variant{}
+	both := Theme{Light: light, Dark: dark}
+	if both.Pick(true) != light || both.Pick(false) != dark {
+		t.Errorf("Pick should choose the matching variant")
+	}
+	if (Theme{Dark: dark}).Pick(true) != dark || (Theme{Light: light}).Pick(false) != light {
+		t.Errorf("Pick should fall back to the only variant")
+	}
+
+	file := &File{Themes: map[string]Theme{"a": both, "b": both}}
+	if _, ok := file.Selected(); ok {
+		t.Errorf("Selected() should fail with several themes and no choice")
+	}
+	file.Themes["default"] = Theme{Dark: dark}
+	if got, ok := file.Selected(); !ok || got.Dark != dark || got.Light != nil {
+		t.Errorf("Selected() should fall back to the default theme")
+	}
+}
+
+func TestVariantApply(t *testing.T) {
+	file, err := Load(writeTheme(t, "theme.toml", solarizedTOML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme, _ := file.Selected()
+
+	p := util.DefaultPalette()
+	if err := theme.Light.Apply(p); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	old := util.DefaultPalette()
+	util.SetPalette(p)
+	t.Cleanup(func() { util.SetPalette(old) })
+
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "src"), 0o755)
+	os.WriteFile(filepath.Join(dir, "a.tar"), make([]byte, 10), 0o644)
+
+	lines, err := util.ReadDirNamesLong(dir, util.Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(lines[1], "\033[04;38;5;160ma.tar\033[0m") {
+		t.Errorf("a.tar line = %q, want the theme's extension style", lines[1])
+	}
+	if !strings.Contains(lines[1], "\033[02;90m10\033[0m") {
+		t.Errorf("a.tar line = %q, want the theme's size column style", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "\033[01;38;2;38;139;210msrc\033[0m") {
+		t.Errorf("src line = %q, want the theme's directory style", lines[2])
+	}
+}
+
+func TestLoad_Icons(t *testing.T) {
+	file, err := Load(writeTheme(t, "theme.json", `{"icons": {"names": {"Justfile": "J"}, "extensions": {"go": "G"}, "types": {"dir": "D"}}}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := &Icons{
+		Names:      map[string]string{"Justfile": "J"},
+		Extensions: map[string]string{"go": "G"},
+		Types:      map[string]string{"dir": "D"},
+	}
+	if !reflect.DeepEqual(file.Icons, want) {
+		t.Errorf("Icons = %+v, want %+v", file.Icons, want)
+	}
+	if err := file.Icons.Apply(util.DefaultIcons()); err != nil {
+		t.Errorf("Apply: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package theme
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Error is a syntax error in a theme file, at a 1-based line
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func errorAt(line int, format string, args ...any) *Error {
+	return &Error{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseTOML reads the subset of TOML theme files need into nested maps:
+// [table] and [dotted."quoted".table] headers, bare, quoted and dotted
+// keys, basic and literal strings, integers, booleans and inline tables.
+// Arrays and dates are not supported
+func parseTOML(r io.Reader) (map[string]any, error) {
+	root := map[string]any{}
+	table := root
+	defined := map[string]bool{}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	for i, text := range strings.Split(string(data), "\n") {
+		line := i + 1
+		s := &tomlScanner{src: strings.TrimSuffix(text, "\r"), line: line}
+		s.skipSpace()
+		if s.done() || s.peek() == '#' {
+			continue
+		}
+
+		if s.peek() == '[' {
+			s.pos++
+			keys, err := s.key()
+			if err != nil {
+				return nil, err
+			}
+			if !s.accept(']') {
+				return nil, errorAt(line, "expected ']' to close the table header")
+			}
+			if err := s.end(); err != nil {
+				return nil, err
+			}
+			name := strings.Join(keys, ".")
+			if defined[name] {
+				return nil, errorAt(line, "table [%v] defined twice", name)
+			}
+			defined[name] = true
+			if table, err = subTable(root, keys, line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := s.keyValue(table); err != nil {
+			return nil, err
+		}
+		if err := s.end(); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// subTable returns the table at keys below t, creating missing tables
+func subTable(t map[string]any, keys []string, line int) (map[string]any, error) {
+	for _, key := range keys {
+		switch next := t[key].(type) {
+		case nil:
+			created := map[string]any{}
+			t[key] = created
+			t = created
+		case map[string]any:
+			t = next
+		default:
+			return nil, errorAt(line, "key %q is already a value, not a table", key)
+		}
+	}
+	return t, nil
+}
+
+// tomlScanner reads one line of a TOML file
+type tomlScanner struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (s *tomlScanner) done() bool {
+	return s.pos >= len(s.src)
+}
+
+func (s *tomlScanner) peek() byte {
+	return s.src[s.pos]
+}
+
+func (s *tomlScanner) skipSpace() {
+	for !s.done() && (s.peek() == ' ' || s.peek() == '\t') {
+		s.pos++
+	}
+}
+
+// accept consumes c, after any spaces, if it comes next
+func (s *tomlScanner) accept(c byte) bool {
+	s.skipSpace()
+	if !s.done() && s.peek() == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// end checks that nothing but a comment follows
+func (s *tomlScanner) end() error {
+	s.skipSpace()
+	if !s.done() && s.peek() != '#' {
+		return errorAt(s.line, "unexpected %q", s.src[s.pos:])
+	}
+	return nil
+}
+
+// keyValue parses "key = value" into t
+func (s *tomlScanner) keyValue(t map[string]any) error {
+	keys, err := s.key()
+	if err != nil {
+		return err
+	}
+	if !s.accept('=') {
+		return errorAt(s.line, "expected '=' after %q", strings.Join(keys, "."))
+	}
+	value, err := s.value()
+	if err != nil {
+		return err
+	}
+	parent, err := subTable(t, keys[:len(keys)-1], s.line)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return errorAt(s.line, "key %q defined twice", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// key parses a dotted key such as a.b."c d"
+func (s *tomlScanner) key() ([]string, error) {
+	var keys []string
+	for {
+		s.skipSpace()
+		if s.done() {
+			return nil, errorAt(s.line, "expected a key")
+		}
+		var key string
+		switch c := s.peek(); {
+		case c == '"' || c == '\'':
+			var err error
+			if key, err = s.str(); err != nil {
+				return nil, err
+			}
+		default:
+			start := s.pos
+			for !s.done() && isBareKeyChar(s.peek()) {
+				s.pos++
+			}
+			if start == s.pos {
+				return nil, errorAt(s.line, "expected a key, got %q", s.src[s.pos:])
+			}
+			key = s.src[start:s.pos]
+		}
+		keys = append(keys, key)
+		if !s.accept('.') {
+			return keys, nil
+		}
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses a string, integer, boolean or inline table
+func (s *tomlScanner) value() (any, error) {
+	s.skipSpace()
+	if s.done() {
+		return nil, errorAt(s.line, "expected a value")
+	}
+	switch c := s.peek(); {
+	case c == '"' || c == '\'':
+		return s.str()
+	case c == '{':
+		s.pos++
+		t := map[string]any{}
+		if s.accept('}') {
+			return t, nil
+		}
+		for {
+			if err := s.keyValue(t); err != nil {
+				return nil, err
+			}
+			if s.accept('}') {
+				return t, nil
+			}
+			if !s.accept(',') {
+				return nil, errorAt(s.line, "expected ',' or '}' in inline table")
+			}
+		}
+	}
+
+	start := s.pos
+	for !s.done() && s.peek() != ' ' && s.peek() != '\t' && s.peek() != ',' && s.peek() != '}' && s.peek() != '#' {
+		s.pos++
+	}
+	word := s.src[start:s.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 0, 64)
+	if err != nil {
+		return nil, errorAt(s.line, "invalid value %q", word)
+	}
+	return n, nil
+}
+
+// str parses a "basic" string with escapes or a 'literal' one without
+func (s *tomlScanner) str() (string, error) {
+	quote := s.peek()
+	s.pos++
+	var b strings.Builder
+	for !s.done() {
+		c := s.peek()
+		s.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if s.done() {
+				return "", errorAt(s.line, "unterminated string")
+			}
+			e := s.peek()
+			s.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'e':
+				b.WriteByte(0x1b)
+			case '"', '\\':
+				b.WriteByte(e)
+			case 'u', 'U':
+				digits := 4
+				if e == 'U' {
+					digits = 8
+				}
+				if s.pos+digits > len(s.src) {
+					return "", errorAt(s.line, "short \\%c escape", e)
+				}
+				r, err := strconv.ParseUint(s.src[s.pos:s.pos+digits], 16, 32)
+				if err != nil {
+					return "", errorAt(s.line, "invalid \\%c escape", e)
+				}
+				b.WriteRune(rune(r))
+				s.pos += digits
+			default:
+				return "", errorAt(s.line, "invalid escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errorAt(s.line, "unterminated string")
+}
@@ -0,0 +1,80 @@
+package theme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `# a theme
+theme = "solarized"   # trailing comment
+count = 0x1f
+neg = -3
+flag = true
+
+[themes.solarized.light.entries]
+dir = { fg = "#268bd2", bold = true }
+"exec" = {fg='green'}
+
+[themes.solarized.light.extensions.".tar.gz"]
+fg = "red"
+escaped = "a\"b\\c\u00e9"
+dotted.key = 'C:\path'
+`
+	got, err := parseTOML(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parseTOML: %v", err)
+	}
+	want := map[string]any{
+		"theme": "solarized",
+		"count": int64(31),
+		"neg":   int64(-3),
+		"flag":  true,
+		"themes": map[string]any{
+			"solarized": map[string]any{
+				"light": map[string]any{
+					"entries": map[string]any{
+						"dir":  map[string]any{"fg": "#268bd2", "bold": true},
+						"exec": map[string]any{"fg": "green"},
+					},
+					"extensions": map[string]any{
+						".tar.gz": map[string]any{
+							"fg":      "red",
+							"escaped": "a\"b\\cé",
+							"dotted":  map[string]any{"key": `C:\path`},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTOML() = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{src: "a = 1\nb 2\n", line: 2},
+		{src: "[table\n", line: 1},
+		{src: "a = \"unterminated\n", line: 1},
+		{src: "a = [1, 2]\n", line: 1},
+		{src: "a = 1\na = 2\n", line: 2},
+		{src: "[t]\n[t]\n", line: 2},
+		{src: "a = 1\n[a]\n", line: 2},
+		{src: "a = 1 b\n", line: 1},
+		{src: "a = {b = 1 c = 2}\n", line: 1},
+		{src: "a = \"\\q\"\n", line: 1},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML(strings.NewReader(tt.src))
+		e, ok := err.(*Error)
+		if !ok || e.Line != tt.line {
+			t.Errorf("parseTOML(%q) error = %v, want one on line %d", tt.src, err, tt.line)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package util
+
+import "os"
+
+const (
+	readOK  = 4 // R_OK
+	writeOK = 2 // W_OK
+	execOK  = 1 // X_OK
+)
+
+// Access returns what the effective user may do with the file at path,
+// following symlinks, as R_OK, W_OK and X_OK bits. The kernel decides, so
+// ACLs, capabilities and read-only mounts count as well as mode bits
+func Access(path string) uint32 {
+	var bits uint32
+	for _, bit := range []uint32{readOK, writeOK, execOK} {
+		if canAccess(path, bit) {
+			bits |= bit
+		}
+	}
+	return bits
+}
+
+// accessFilter keeps the entries the effective user may read, write or
+// run (or search), like find's -readable, -writable and -executable
+func accessFilter(bit uint32) Predicate {
+	return func(e Entry) bool {
+		return canAccess(e.Path, bit)
+	}
+}
+
+// accessString spells out bits from Access as rwx
+func accessString(bits uint32) string {
+	return rwx(os.FileMode(bits))
+}
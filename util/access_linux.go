@@ -0,0 +1,24 @@
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+const atEAccess = 0x200 // AT_EACCESS: check as the effective user, as open(2) would
+
+// accessFlags returns the faccessat(2) flags that check as the effective
+// user. When the real and effective ids match, plain faccessat gives the
+// same answer, and unlike AT_EACCESS it needs no faccessat2 (Linux 5.8),
+// without which Go falls back to comparing mode bits
+func accessFlags() int {
+	if os.Geteuid() == os.Getuid() && os.Getegid() == os.Getgid() {
+		return 0
+	}
+	return atEAccess
+}
+
+// canAccess reports whether the effective user may access path as bit asks
+func canAccess(path string, bit uint32) bool {
+	return syscall.Faccessat(atFDCWD, path, bit, accessFlags()) == nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package util
+
+import "syscall"
+
+// canAccess reports whether the user may access path as bit asks. access(2)
+// checks as the real user, who is the effective one unless my-ls runs setuid
+func canAccess(path string, bit uint32) bool {
+	return syscall.Access(path, bit) == nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAccess(t *testing.T) {
+	base := t.TempDir()
+	root := os.Geteuid() == 0
+	tests := []struct {
+		name string
+		mode os.FileMode
+		want string
+	}{
+		{name: "private", mode: 0o600, want: "rw-"},
+		{name: "script", mode: 0o700, want: "rwx"},
+		{name: "read-only", mode: 0o400, want: map[bool]string{false: "r--", true: "rw-"}[root]},
+		{name: "none", mode: 0, want: map[bool]string{false: "---", true: "rw-"}[root]},
+	}
+	for _, tt := range tests {
+		path := joinPath(base, tt.name)
+		os.WriteFile(path, nil, 0o600)
+		os.Chmod(path, tt.mode)
+		if got := accessString(Access(path)); got != tt.want {
+			t.Errorf("Access(%v) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := Access(joinPath(base, "missing")); got != 0 {
+		t.Errorf("Access(missing) = %o, want nothing", got)
+	}
+}
+
+func TestReadDirNamesLong_Access(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "f"), nil, 0o700)
+
+	lines, err := ReadDirNamesLong(base, Flags{Columns: []string{"access", "name"}, NoColor: true})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	if lines[1] != "rwx f" {
+		t.Errorf("line = %q, want %q", lines[1], "rwx f")
+	}
+}
@@ -0,0 +1,7 @@
+package util
+
+// Values of the *at(2) family's dirfd and flags arguments
+const (
+	atFDCWD           = -0x64
+	atSymlinkNoFollow = 0x100
+)
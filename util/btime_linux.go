@@ -0,0 +1,39 @@
+//go:build linux && (amd64 || arm64)
+
+package util
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	statxBtime       = 0x800 // STATX_BTIME
+	statxBtimeOffset = 80    // of stx_btime in struct statx
+)
+
+// BirthTime returns when the file at path, or with follow the file a
+// symlink there points to, was created. Only statx(2) reports it, and only
+// on file systems that record it
+func BirthTime(path string, follow bool) (time.Time, bool) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var buf [32]uint64 // struct statx, 8-byte aligned for the reads below
+	dirfd := atFDCWD
+	flags := atSymlinkNoFollow
+	if follow {
+		flags = 0
+	}
+	_, _, errno := syscall.Syscall6(sysStatx, uintptr(dirfd), uintptr(unsafe.Pointer(p)),
+		uintptr(flags), statxBtime, uintptr(unsafe.Pointer(&buf)), 0)
+	base := unsafe.Pointer(&buf)
+	if errno != 0 || *(*uint32)(base)&statxBtime == 0 { // stx_mask
+		return time.Time{}, false
+	}
+	sec := *(*int64)(unsafe.Add(base, statxBtimeOffset))
+	nsec := *(*uint32)(unsafe.Add(base, statxBtimeOffset+8))
+	return time.Unix(sec, int64(nsec)), true
+}
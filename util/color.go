@@ -0,0 +1,26 @@
+package util
+
+import "fmt"
+
+// ColorMode is the --color setting
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // colour only when writing to a terminal
+	ColorAlways                  // colour even into pipes and files
+	ColorNever                   // never colour
+)
+
+// ParseColorMode parses a --color argument, accepting the same spellings as
+// GNU ls. An empty value, from a bare --color, means always
+func ParseColorMode(s string) (ColorMode, error) {
+	switch s {
+	case "", "always", "yes", "force":
+		return ColorAlways, nil
+	case "never", "no", "none":
+		return ColorNever, nil
+	case "auto", "tty", "if-tty":
+		return ColorAuto, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid argument '%v' for '--color' (valid: always, never, auto)", s)
+}
@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestParseColorMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ColorMode
+		wantErr bool
+	}{
+		{in: "", want: ColorAlways},
+		{in: "always", want: ColorAlways},
+		{in: "yes", want: ColorAlways},
+		{in: "force", want: ColorAlways},
+		{in: "never", want: ColorNever},
+		{in: "no", want: ColorNever},
+		{in: "none", want: ColorNever},
+		{in: "auto", want: ColorAuto},
+		{in: "tty", want: ColorAuto},
+		{in: "if-tty", want: ColorAuto},
+		{in: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColorMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColorMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseColorMode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ColorScale says which long-format cells are coloured on a gradient
+// (--color-scale)
+type ColorScale struct {
+	Age  bool // the date column, bright for recent files and dim for old ones
+	Size bool // the size column, from cool for small files to hot for large ones
+	Name bool // names of plain files, on the age gradient
+}
+
+// ParseColorScale parses a comma-separated --color-scale list of age, size
+// and name. An empty value, from a bare --color-scale, and "all" mean age,size
+func ParseColorScale(s string) (ColorScale, error) {
+	if s == "" || s == "all" {
+		return ColorScale{Age: true, Size: true}, nil
+	}
+	var scale ColorScale
+	for _, field := range strings.Split(s, ",") {
+		switch field {
+		case "age":
+			scale.Age = true
+		case "size":
+			scale.Size = true
+		case "name":
+			scale.Name = true
+		default:
+			return ColorScale{}, fmt.Errorf("invalid argument '%v' for '--color-scale' (valid: age, size, name, all)", field)
+		}
+	}
+	return scale, nil
+}
+
+// rgb is a 24-bit colour
+type rgb struct{ r, g, b uint8 }
+
+// The gradients: ages fade from white to dark grey, sizes run from green
+// through yellow to red
+var (
+	ageStops  = []rgb{{0xff, 0xff, 0xff}, {0xbc, 0xbc, 0xbc}, {0x58, 0x58, 0x58}}
+	sizeStops = []rgb{{0x87, 0xd7, 0x87}, {0xff, 0xd7, 0x5f}, {0xff, 0x5f, 0x5f}}
+)
+
+// The ends of the logarithmic scales: anything newer than a minute is
+// brightest and anything older than five years dimmest, and likewise for
+// sizes from 1KiB to 1GiB
+const (
+	ageFloor  = time.Minute
+	ageCeil   = 5 * 365 * 24 * time.Hour
+	sizeFloor = 1 << 10
+	sizeCeil  = 1 << 30
+)
+
+// ageColour returns the SGR parameters for a file last modified age ago
+func ageColour(age time.Duration, trueColor bool) string {
+	return gradient(ageStops, logPosition(float64(age), float64(ageFloor), float64(ageCeil))).sgr(trueColor)
+}
+
+// sizeColour returns the SGR parameters for a file of size bytes
+func sizeColour(size int64, trueColor bool) string {
+	return gradient(sizeStops, logPosition(float64(size), sizeFloor, sizeCeil)).sgr(trueColor)
+}
+
+// logPosition places v between lo and hi on a logarithmic scale, from 0 to 1
+func logPosition(v, lo, hi float64) float64 {
+	if v <= lo {
+		return 0
+	}
+	if v >= hi {
+		return 1
+	}
+	return math.Log(v/lo) / math.Log(hi/lo)
+}
+
+// gradient returns the colour at t, from 0 to 1, along evenly spaced stops
+func gradient(stops []rgb, t float64) rgb {
+	segment := t * float64(len(stops)-1)
+	i := int(segment)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	frac := segment - float64(i)
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*frac))
+	}
+	a, b := stops[i], stops[i+1]
+	return rgb{mix(a.r, b.r), mix(a.g, b.g), mix(a.b, b.b)}
+}
+
+// sgr returns the foreground SGR parameters for c, as a 24-bit colour or
+// as the nearest entry of the 256-colour palette
+func (c rgb) sgr(trueColor bool) string {
+	if trueColor {
+		return fmt.Sprintf("38;2;%d;%d;%d", c.r, c.g, c.b)
+	}
+	return fmt.Sprintf("38;5;%d", c.xterm256())
+}
+
+// cubeLevels are the channel values of the 6x6x6 colour cube in the 256-colour palette
+var cubeLevels = [6]int{0, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// xterm256 returns the closest 256-colour palette index to c, from the
+// colour cube or the grey ramp
+func (c rgb) xterm256() int {
+	nearestLevel := func(v uint8) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if abs(int(v)-level) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearestLevel(c.r), nearestLevel(c.g), nearestLevel(c.b)
+	cube := 16 + 36*r + 6*g + b
+	cubeColour := rgb{uint8(cubeLevels[r]), uint8(cubeLevels[g]), uint8(cubeLevels[b])}
+
+	// The grey ramp runs from 8 to 238 in steps of 10
+	average := (int(c.r) + int(c.g) + int(c.b)) / 3
+	step := min(max((average-8+5)/10, 0), 23)
+	grey := uint8(8 + 10*step)
+	if c.distance(rgb{grey, grey, grey}) < c.distance(cubeColour) {
+		return 232 + step
+	}
+	return cube
+}
+
+func (c rgb) distance(o rgb) int {
+	dr, dg, db := int(c.r)-int(o.r), int(c.g)-int(o.g), int(c.b)-int(o.b)
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseColorScale(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ColorScale
+		wantErr bool
+	}{
+		{in: "", want: ColorScale{Age: true, Size: true}},
+		{in: "all", want: ColorScale{Age: true, Size: true}},
+		{in: "age", want: ColorScale{Age: true}},
+		{in: "size,name", want: ColorScale{Size: true, Name: true}},
+		{in: "age,heat", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColorScale(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseColorScale(%q) = %+v, %v; want %+v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGradientColours(t *testing.T) {
+	tests := []struct {
+		name      string
+		got       string
+		trueColor string
+	}{
+		{name: "brand new", got: ageColour(time.Second, true), trueColor: "38;2;255;255;255"},
+		{name: "ancient", got: ageColour(10*365*24*time.Hour, true), trueColor: "38;2;88;88;88"},
+		{name: "tiny", got: sizeColour(10, true), trueColor: "38;2;135;215;135"},
+		{name: "huge", got: sizeColour(5<<30, true), trueColor: "38;2;255;95;95"},
+		{name: "brand new, 256 colours", got: ageColour(0, false), trueColor: "38;5;231"},
+		{name: "ancient, 256 colours", got: ageColour(ageCeil, false), trueColor: "38;5;240"},
+		{name: "huge, 256 colours", got: sizeColour(sizeCeil, false), trueColor: "38;5;203"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.trueColor {
+			t.Errorf("%s: colour = %q, want %q", tt.name, tt.got, tt.trueColor)
+		}
+	}
+
+	// Older files are never brighter and larger files never cooler
+	if a, b := gradient(ageStops, logPosition(float64(time.Hour), float64(ageFloor), float64(ageCeil))),
+		gradient(ageStops, logPosition(float64(30*24*time.Hour), float64(ageFloor), float64(ageCeil))); a.r < b.r {
+		t.Errorf("an hour old (%v) should be brighter than a month old (%v)", a, b)
+	}
+	if a, b := gradient(sizeStops, logPosition(1<<20, sizeFloor, sizeCeil)),
+		gradient(sizeStops, logPosition(1<<28, sizeFloor, sizeCeil)); a.g < b.g {
+		t.Errorf("1M (%v) should be cooler than 256M (%v)", a, b)
+	}
+}
+
+func TestXterm256(t *testing.T) {
+	tests := []struct {
+		c    rgb
+		want int
+	}{
+		{c: rgb{0, 0, 0}, want: 16},
+		{c: rgb{0xff, 0, 0}, want: 196},
+		{c: rgb{0x87, 0xd7, 0x87}, want: 114},
+		{c: rgb{0x80, 0x80, 0x80}, want: 244},
+		{c: rgb{0xff, 0xff, 0xff}, want: 231},
+	}
+	for _, tt := range tests {
+		if got := tt.c.xterm256(); got != tt.want {
+			t.Errorf("%v.xterm256() = %d, want %d", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestReadDirNamesLong_ColorScale(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(joinPath(dir, "new"), make([]byte, 100), 0o644)
+	os.WriteFile(joinPath(dir, "old"), make([]byte, 5000), 0o644)
+	old := time.Now().Add(-10 * 365 * 24 * time.Hour)
+	os.Chtimes(joinPath(dir, "old"), old, old)
+
+	lines, err := ReadDirNamesLong(dir, Flags{ColorScale: ColorScale{Age: true, Size: true, Name: true}, TrueColor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLine, oldLine := lines[1], lines[2]
+
+	if !strings.Contains(oldLine, "\033[38;2;88;88;88m"+old.Format("Jan _2 15:04")+reset) {
+		t.Errorf("old file's date should be dim: %q", oldLine)
+	}
+	if !strings.HasSuffix(oldLine, "\033[38;2;88;88;88mold"+reset) {
+		t.Errorf("old file's name should be on the age gradient: %q", oldLine)
+	}
+	if !strings.Contains(newLine, "  \033["+sizeColour(100, true)+"m100"+reset) {
+		t.Errorf("new file's size should be padded outside its colour: %q", newLine)
+	}
+	if strings.Contains(newLine, "\033[38;2;88;88;88m") {
+		t.Errorf("new file should not be dim: %q", newLine)
+	}
+
+	plain, _ := ReadDirNamesLong(dir, Flags{ColorScale: ColorScale{Age: true, Size: true}, NoColor: true})
+	for _, line := range plain {
+		if strings.Contains(line, "\033") {
+			t.Errorf("NoColor line %q contains an escape sequence", line)
+		}
+	}
+}
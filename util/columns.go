@@ -0,0 +1,258 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// longRecord is an entry of a long listing with what its columns are drawn from
+type longRecord struct {
+	os.FileInfo
+	path string
+	stat *syscall.Stat_t
+	name string // the displayed name, with its icon and link target or indicator
+	git  string // the git status, when there is a git column
+
+	// follow reads path through a symlink, for an entry -L has resolved
+	follow bool
+
+	xattrs []string // the names of its extended attributes, once read
+}
+
+// xattrNames returns the names of the record's extended attributes,
+// reading them the first time
+func (r *longRecord) xattrNames() []string {
+	if r.xattrs == nil {
+		r.xattrs = ListXattrs(r.path, r.follow)
+		if r.xattrs == nil {
+			r.xattrs = []string{}
+		}
+	}
+	return r.xattrs
+}
+
+// longColumn is a field of a long listing: its heading, the side it is
+// padded on, and how its cell is drawn, coloured, for a record
+type longColumn struct {
+	header string
+	left   bool
+	cell   func(r *longRecord, flag Flags) string
+}
+
+// longColumns are the fields --columns can choose from
+var longColumns = map[string]longColumn{
+	"inode": {header: "Inode", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "inode", fmt.Sprint(r.stat.Ino))
+	}},
+	"blocks": {header: "Blocks", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "blocks", flag.BlockSize.Format(int64(r.stat.Blocks)*512, 1024))
+	}},
+	"perms": {header: "Permissions", left: true, cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "mode", ModeString(r.Mode())+xattrIndicator(r.xattrNames()))
+	}},
+	"octal": {header: "Octal", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "mode", fmt.Sprintf("%04o", PermBits(r.Mode())))
+	}},
+	"explain": {header: "Explain", left: true, cell: func(r *longRecord, flag Flags) string {
+		group := GroupName(r.stat.Gid)
+		if flag.NumericIDs {
+			group = fmt.Sprint(r.stat.Gid)
+		}
+		return paintColumn(flag, "mode", explainPerms(processCredentials(), r.stat.Uid, r.stat.Gid, r.Mode(), group))
+	}},
+	"access": {header: "Access", left: true, cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "mode", accessString(Access(r.path)))
+	}},
+	"links": {header: "Links", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "links", fmt.Sprint(r.stat.Nlink))
+	}},
+	"user": {header: "User", left: true, cell: func(r *longRecord, flag Flags) string {
+		if flag.NumericIDs {
+			return paintColumn(flag, "user", fmt.Sprint(r.stat.Uid))
+		}
+		return paintColumn(flag, "user", UserName(r.stat.Uid))
+	}},
+	"group": {header: "Group", left: true, cell: func(r *longRecord, flag Flags) string {
+		if flag.NumericIDs {
+			return paintColumn(flag, "group", fmt.Sprint(r.stat.Gid))
+		}
+		return paintColumn(flag, "group", GroupName(r.stat.Gid))
+	}},
+	"uid": {header: "UID", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "user", fmt.Sprint(r.stat.Uid))
+	}},
+	"gid": {header: "GID", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "group", fmt.Sprint(r.stat.Gid))
+	}},
+	"size": {header: "Size", cell: func(r *longRecord, flag Flags) string {
+		colour := palette.columns["size"]
+		if flag.ColorScale.Size {
+			colour = sizeColour(r.Size(), flag.TrueColor)
+		}
+		return paint(flag, palette.start(colour), flag.FileSize.Format(r.Size(), 1))
+	}},
+	"mtime": timeColumn("Modified", func(r *longRecord) (time.Time, bool) {
+		return r.ModTime(), true
+	}),
+	"atime": timeColumn("Accessed", func(r *longRecord) (time.Time, bool) {
+		return AccessTime(r.stat), true
+	}),
+	"ctime": timeColumn("Changed", func(r *longRecord) (time.Time, bool) {
+		return ChangeTime(r.stat), true
+	}),
+	"btime": timeColumn("Created", func(r *longRecord) (time.Time, bool) {
+		return BirthTime(r.path, r.follow)
+	}),
+	"git": {header: "Git", left: true, cell: func(r *longRecord, flag Flags) string {
+		if r.git == "" {
+			return paintColumn(flag, "git", "--")
+		}
+		return paintColumn(flag, "git", strings.ReplaceAll(r.git, " ", "-"))
+	}},
+	"xattr": {header: "Xattrs", cell: func(r *longRecord, flag Flags) string {
+		return paintColumn(flag, "xattr", fmt.Sprint(len(r.xattrNames())))
+	}},
+	"name": {header: "Name", left: true, cell: func(r *longRecord, flag Flags) string {
+		return r.name
+	}},
+}
+
+// columnNames are the --columns names in the order they are listed in errors
+var columnNames = []string{
+	"inode", "blocks", "perms", "octal", "explain", "access", "links", "user", "group", "uid", "gid",
+	"size", "mtime", "atime", "ctime", "btime", "git", "xattr", "name",
+}
+
+// timeColumn builds a date column from one of an entry's times, shown as
+// "-" when the file system does not keep it. --color-scale=age colours it
+// by its own age
+func timeColumn(header string, when func(r *longRecord) (time.Time, bool)) longColumn {
+	return longColumn{header: header, cell: func(r *longRecord, flag Flags) string {
+		t, ok := when(r)
+		if !ok {
+			return "-"
+		}
+		colour := palette.columns["date"]
+		if flag.ColorScale.Age {
+			colour = ageColour(time.Since(t), flag.TrueColor)
+		}
+		return paint(flag, palette.start(colour), FormatTime(t))
+	}}
+}
+
+// ParseColumns parses a --columns list
+func ParseColumns(s string) ([]string, error) {
+	columns := strings.Split(s, ",")
+	for _, name := range columns {
+		if _, ok := longColumns[name]; !ok {
+			return nil, fmt.Errorf("invalid column '%v' for '--columns' (valid: %v)", name, strings.Join(columnNames, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// longColumnNames returns the columns of a long listing: those given with
+// --columns, or the ones -i, -s, -g, -o, -G, --octal-perms, --perms-explain,
+// --access and --grid-long pick
+func longColumnNames(flag Flags) []string {
+	if flag.Columns != nil {
+		return flag.Columns
+	}
+	var columns []string
+	if flag.Inode {
+		columns = append(columns, "inode")
+	}
+	if flag.AllocSize {
+		columns = append(columns, "blocks")
+	}
+	if flag.OctalPerms {
+		columns = append(columns, "octal")
+	}
+	columns = append(columns, "perms")
+	if flag.PermsExplain {
+		columns = append(columns, "explain")
+	}
+	if flag.Access {
+		columns = append(columns, "access")
+	}
+	if !flag.GridLong {
+		columns = append(columns, "links")
+		if !flag.NoOwner {
+			columns = append(columns, "user")
+		}
+		if !flag.NoGroup {
+			columns = append(columns, "group")
+		}
+	}
+	return append(columns, "size", "mtime", "name")
+}
+
+// hasColumn reports whether name is among columns
+func hasColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRecords draws records as lines of cells in the named columns, each
+// padded to the widest cell in its column and a space apart. With header,
+// the first line holds the columns' headings. A left-aligned last column is
+// not padded, so lines carry no trailing spaces
+func formatRecords(records []*longRecord, columns []string, flag Flags, header bool) []string {
+	var rows [][]string
+	if header {
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			row[i] = longColumns[name].header
+		}
+		rows = append(rows, row)
+	}
+	for _, r := range records {
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			row[i] = longColumns[name].cell(r, flag)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], DisplayWidth(cell))
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for j, row := range rows {
+		for i, cell := range row {
+			left := longColumns[columns[i]].left
+			if left && i == len(row)-1 {
+				continue
+			}
+			row[i] = pad(cell, widths[i], left)
+		}
+		lines[j] = strings.Join(row, " ")
+	}
+	return lines
+}
+
+// pad pads text, which may be coloured, to width cells: after it when left
+// is set, and before it otherwise
+func pad(text string, width int, left bool) string {
+	fill := spaces(width - DisplayWidth(text))
+	if left {
+		return text + fill
+	}
+	return fill + text
+}
+
+// paintColumn paints text in the palette's colour for the named column
+func paintColumn(flag Flags, name, text string) string {
+	return paint(flag, palette.start(palette.columns[name]), text)
+}
@@ -0,0 +1,256 @@
+package util
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("name,size,perms")
+	if err != nil || !reflect.DeepEqual(columns, []string{"name", "size", "perms"}) {
+		t.Errorf("ParseColumns() = %v, %v", columns, err)
+	}
+	for _, bad := range []string{"", "name,,size", "colour", "Size"} {
+		if _, err := ParseColumns(bad); err == nil {
+			t.Errorf("ParseColumns(%q) expected an error", bad)
+		}
+	}
+}
+
+func TestLongColumnNames(t *testing.T) {
+	tests := []struct {
+		flags Flags
+		want  []string
+	}{
+		{flags: Flags{}, want: []string{"perms", "links", "user", "group", "size", "mtime", "name"}},
+		{flags: Flags{Inode: true, NoOwner: true}, want: []string{"inode", "perms", "links", "group", "size", "mtime", "name"}},
+		{flags: Flags{GridLong: true, AllocSize: true}, want: []string{"blocks", "perms", "size", "mtime", "name"}},
+		{flags: Flags{Inode: true, Columns: []string{"name", "size"}}, want: []string{"name", "size"}},
+	}
+	for _, tt := range tests {
+		if got := longColumnNames(tt.flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("longColumnNames(%+v) = %v, want %v", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestReadDirNamesLong_SelectedColumns(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "a.txt"), make([]byte, 12345), 0o755)
+	os.WriteFile(joinPath(base, "b"), nil, 0o600)
+	os.Chmod(joinPath(base, "a.txt"), 0o755|os.ModeSetuid)
+
+	lines, err := ReadDirNamesLong(base, Flags{Columns: []string{"name", "octal", "size"}, Header: true, NoColor: true})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	want := []string{
+		"Name  Octal  Size",
+		"a.txt  4755 12345",
+		"b      0600     0",
+	}
+	if !reflect.DeepEqual(lines[1:], want) {
+		t.Errorf("lines = %q, want %q", lines[1:], want)
+	}
+}
+
+func TestFormatRecords_NoTrailingSpaces(t *testing.T) {
+	records := []*longRecord{{name: "long-name"}, {name: "x"}}
+	lines := formatRecords(records, []string{"name"}, Flags{NoColor: true}, true)
+	if want := []string{"Name", "long-name", "x"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("formatRecords() = %q, want %q", lines, want)
+	}
+}
+
+func TestReadDirNamesLong_PaintedColumns(t *testing.T) {
+	old := palette
+	t.Cleanup(func() { palette = old })
+	p := DefaultPalette()
+	p.SetColumn("git", "33")
+	p.SetColumn("xattr", "35")
+	SetPalette(p)
+
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "f"), nil, 0o644)
+
+	// Outside a work tree every entry is clean
+	lines, err := ReadDirNamesLong(base, Flags{Columns: []string{"git", "xattr"}})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	if want := "\033[33m--\033[0m \033[35m0\033[0m"; lines[1] != want {
+		t.Errorf("line = %q, want %q", lines[1], want)
+	}
+}
+
+func TestMergeGitStatus(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{a: "", b: " M", want: " M"},
+		{a: " M", b: "A ", want: "AM"},
+		{a: "!!", b: "??", want: "??"},
+		{a: "!!", b: "!!", want: "!!"},
+		{a: "??", b: " M", want: " M"},
+		{a: " M", b: "??", want: " M"},
+		{a: "??", b: "??", want: "??"},
+		{a: "A ", b: "!!", want: "A "},
+	}
+	for _, tt := range tests {
+		if got := mergeGitStatus(tt.a, tt.b); got != tt.want {
+			t.Errorf("mergeGitStatus(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// writeGitIndex makes repo a work tree whose version 2 index tracks paths
+// as they are now, as git add would leave it
+func writeGitIndex(t *testing.T, repo string, paths ...string) {
+	t.Helper()
+	if err := os.MkdirAll(joinPath(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	be32 := func(b []byte, v uint32) []byte {
+		return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	sort.Strings(paths)
+	index := be32(be32([]byte("DIRC"), 2), uint32(len(paths)))
+	for _, path := range paths {
+		info, err := os.Lstat(joinPath(repo, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := be32(be32(make([]byte, 8), uint32(info.ModTime().Unix())), uint32(info.ModTime().Nanosecond()))
+		entry = be32(append(entry, make([]byte, 8)...), 0o100000|uint32(info.Mode().Perm()))
+		entry = be32(append(entry, make([]byte, 8)...), uint32(info.Size()))
+		entry = append(entry, make([]byte, 20)...) // object name
+		entry = append(entry, byte(len(path)>>8), byte(len(path)))
+		entry = append(entry, path...)
+		index = append(index, entry...)
+		index = append(index, make([]byte, 8-len(entry)%8)...)
+	}
+	index = append(index, make([]byte, 20)...) // checksum, unchecked
+	if err := os.WriteFile(joinPath(repo, ".git/index"), index, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitStatuses(t *testing.T) {
+	repo := t.TempDir()
+	os.Mkdir(joinPath(repo, "sub"), 0o755)
+	os.Mkdir(joinPath(repo, "gone"), 0o755)
+	os.Mkdir(joinPath(repo, "empty"), 0o755)
+	os.WriteFile(joinPath(repo, "sub/tracked"), []byte("a"), 0o644)
+	os.WriteFile(joinPath(repo, "gone/file"), []byte("a"), 0o644)
+	os.WriteFile(joinPath(repo, "clean"), []byte("a"), 0o644)
+	os.WriteFile(joinPath(repo, ".gitignore"), []byte("ignored\n*.log\n!keep.log\n"), 0o644)
+	writeGitIndex(t, repo, "sub/tracked", "gone/file", "clean", ".gitignore")
+	os.WriteFile(joinPath(repo, "sub/tracked"), []byte("bb"), 0o644)
+	os.WriteFile(joinPath(repo, "sub/untracked"), nil, 0o644)
+	os.Remove(joinPath(repo, "gone/file"))
+	for _, name := range []string{"new", "ignored", "debug.log", "keep.log"} {
+		os.WriteFile(joinPath(repo, name), nil, 0o644)
+	}
+
+	statuses := gitStatuses(repo)
+	want := map[string]string{"sub": " M", "gone": " D", "new": "??", "ignored": "!!", "debug.log": "!!", "keep.log": "??"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("gitStatuses() = %q, want %q", statuses, want)
+	}
+	if got := gitStatuses(joinPath(repo, "sub")); got["tracked"] != " M" || got["untracked"] != "??" {
+		t.Errorf("gitStatuses(sub) = %q, want tracked modified and untracked as ??", got)
+	}
+	if got := gitStatuses(t.TempDir()); got != nil {
+		t.Errorf("gitStatuses() outside a repository = %q, want none", got)
+	}
+
+	lines, _ := ReadDirNamesLong(repo, Flags{Columns: []string{"git", "name"}, NoColor: true})
+	if !strings.Contains(strings.Join(lines, "\n"), "-- clean") {
+		t.Errorf("lines = %q, want clean shown as --", lines)
+	}
+}
+
+func TestReadGitIndex_Version4(t *testing.T) {
+	// Two entries, the second path sharing "dir/" with the first
+	entry := func(strip byte, suffix string) []byte {
+		e := make([]byte, 40+20)
+		e[26], e[27], e[39] = 0x81, 0xa4, 3 // mode 0100644, size
+		e = append(e, 0, byte(len(suffix)))
+		e = append(e, strip)
+		return append(append(e, suffix...), 0)
+	}
+	data := []byte("DIRC\x00\x00\x00\x04\x00\x00\x00\x02")
+	data = append(data, entry(0, "dir/a")...)
+	data = append(data, entry(1, "bc")...)
+	path := joinPath(t.TempDir(), "index")
+	os.WriteFile(path, data, 0o644)
+
+	entries, err := readGitIndex(path, 20)
+	if err != nil || len(entries) != 2 || entries[0].path != "dir/a" || entries[1].path != "dir/bc" || entries[1].size != 3 {
+		t.Errorf("readGitIndex() = %+v, %v", entries, err)
+	}
+	if _, err := readGitIndex(path, 32); err == nil {
+		t.Errorf("readGitIndex() with the wrong hash size should fail")
+	}
+}
+
+func TestGitIgnore(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(joinPath(dir, "top"), []byte("# comment\n*.o\n/root-only\nbuild/\ndoc/**/*.html\n!keep.o\n\\#hash\n"), 0o644)
+	os.WriteFile(joinPath(dir, "sub"), []byte("local\n"), 0o644)
+	ignore := &gitIgnore{}
+	ignore.load(joinPath(dir, "top"), "")
+	ignore.load(joinPath(dir, "sub"), "src/")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "a.o", want: true},
+		{path: "src/deep/a.o", want: true},
+		{path: "keep.o"},
+		{path: "root-only", want: true},
+		{path: "src/root-only"},
+		{path: "build", isDir: true, want: true},
+		{path: "build"},
+		{path: "doc/x.html", want: true},
+		{path: "doc/a/b/x.html", want: true},
+		{path: "docs/x.html"},
+		{path: "#hash", want: true},
+		{path: "src/local", want: true},
+		{path: "local"},
+	}
+	for _, tt := range tests {
+		if got := ignore.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestBirthTime(t *testing.T) {
+	path := joinPath(t.TempDir(), "f")
+	os.WriteFile(path, nil, 0o644)
+	btime, ok := BirthTime(path, false)
+	if !ok {
+		t.Skip("file system does not record birth times")
+	}
+	info, _ := os.Stat(path)
+	if diff := info.ModTime().Sub(btime); diff < 0 || diff > 5e9 {
+		t.Errorf("BirthTime() = %v, want about the modification time %v", btime, info.ModTime())
+	}
+	if _, ok := BirthTime(joinPath(t.TempDir(), "missing"), false); ok {
+		t.Errorf("BirthTime() of a missing file reported a time")
+	}
+
+	// A dangling link has a birth time of its own, but following it finds nothing
+	link := joinPath(t.TempDir(), "dangling")
+	os.Symlink("missing", link)
+	if _, ok := BirthTime(link, false); !ok {
+		t.Errorf("BirthTime(link, false) should report the link's own time")
+	}
+	if _, ok := BirthTime(link, true); ok {
+		t.Errorf("BirthTime(link, true) should follow the link to nothing")
+	}
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// dircolorsKeywords maps dircolors database keywords to LS_COLORS keys
+var dircolorsKeywords = map[string]string{
+	"NORMAL":                "no",
+	"NORM":                  "no",
+	"FILE":                  "fi",
+	"RESET":                 "rs",
+	"DIR":                   "di",
+	"LNK":                   "ln",
+	"LINK":                  "ln",
+	"SYMLINK":               "ln",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"DOOR":                  "do",
+	"EXEC":                  "ex",
+	"LEFT":                  "lc",
+	"LEFTCODE":              "lc",
+	"RIGHT":                 "rc",
+	"RIGHTCODE":             "rc",
+	"END":                   "ec",
+	"ENDCODE":               "ec",
+	"SUID":                  "su",
+	"SETUID":                "su",
+	"SGID":                  "sg",
+	"SETGID":                "sg",
+	"STICKY":                "st",
+	"OTHER_WRITABLE":        "ow",
+	"OWR":                   "ow",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OWT":                   "tw",
+	"CAPABILITY":            "ca",
+	"MULTIHARDLINK":         "mh",
+	"CLRTOEOL":              "cl",
+}
+
+// dircolorsIgnored are keywords older databases use that no longer mean anything
+var dircolorsIgnored = map[string]bool{"OPTIONS": true, "COLOR": true, "EIGHTBIT": true}
+
+// Dircolors reads a database in the coreutils dircolors format and returns
+// the matching LS_COLORS value. TERM and COLORTERM lines hold glob patterns;
+// the entries after a run of them apply only when term or colorterm matches
+// one, and entries before any apply everywhere
+func Dircolors(r io.Reader, term, colorterm string) (string, error) {
+	const (
+		global   = iota // no TERM or COLORTERM line seen yet
+		termNo          // the latest run of TERM lines did not match
+		termSure        // a line in the current run matched
+		termYes         // entries are being taken after a matching run
+	)
+	state := global
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []string
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		keyword, arg, ok := splitDircolorsLine(strings.TrimSuffix(line, "\r"))
+		if !ok {
+			continue
+		}
+		if arg == "" {
+			return "", fmt.Errorf("line %d: missing argument for %v", lineNo, keyword)
+		}
+
+		switch upperKeyword := strings.ToUpper(keyword); upperKeyword {
+		case "TERM", "COLORTERM":
+			value := term
+			if upperKeyword == "COLORTERM" {
+				value = colorterm
+			}
+			if state != termSure {
+				state = termNo
+				if ok, _ := path.Match(arg, value); ok {
+					state = termSure
+				}
+			}
+			continue
+		}
+
+		if state == termSure {
+			state = termYes // another TERM line starts a new run
+		}
+		if state == termNo {
+			continue
+		}
+
+		switch {
+		case keyword[0] == '.':
+			entries = append(entries, "*"+keyword+"="+arg)
+		case keyword[0] == '*':
+			entries = append(entries, keyword+"="+arg)
+		case dircolorsIgnored[strings.ToUpper(keyword)]:
+		default:
+			key, known := dircolorsKeywords[strings.ToUpper(keyword)]
+			if !known {
+				return "", fmt.Errorf("line %d: unrecognized keyword %v", lineNo, keyword)
+			}
+			entries = append(entries, key+"="+arg)
+		}
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(entry)
+		b.WriteByte(':')
+	}
+	return b.String(), nil
+}
+
+// splitDircolorsLine returns the keyword and argument on a database line,
+// with comments removed. A # starts a comment at the start of the line or
+// after whitespace; ok is false for blank lines
+func splitDircolorsLine(line string) (keyword, arg string, ok bool) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	if len(fields) == 1 {
+		return fields[0], "", true
+	}
+	return fields[0], fields[1], true
+}
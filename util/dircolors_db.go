@@ -0,0 +1,190 @@
+package util
+
+// DircolorsDatabase is the built-in database `my-ls dircolors` uses when
+// given no file, and prints with --print-database. Its type colours match
+// the colours my-ls uses when LS_COLORS is not set
+const DircolorsDatabase = `# Configuration file for my-ls dircolors, in the coreutils dircolors format.
+# Copy it with 'my-ls dircolors --print-database > ~/.dircolors', edit it,
+# and load it with 'eval "$(my-ls dircolors ~/.dircolors)"'.
+
+# Lines below a run of TERM or COLORTERM lines only apply when the terminal
+# matches one of those glob patterns.
+COLORTERM ?*
+TERM Eterm
+TERM alacritty*
+TERM ansi
+TERM *color*
+TERM con[0-9]*x[0-9]*
+TERM cons25
+TERM console
+TERM cygwin
+TERM *direct*
+TERM dtterm
+TERM foot
+TERM gnome
+TERM hurd
+TERM jfbterm
+TERM kitty
+TERM konsole
+TERM kterm
+TERM linux
+TERM linux-c
+TERM mlterm
+TERM putty
+TERM rxvt*
+TERM screen*
+TERM st
+TERM terminator
+TERM tmux*
+TERM vt100
+TERM wezterm*
+TERM xterm*
+
+# Attribute codes:  00=none 01=bold 04=underscore 05=blink 07=reverse 08=concealed
+# Text colour codes: 30=black 31=red 32=green 33=yellow 34=blue 35=magenta 36=cyan 37=white
+# Background codes:  40=black 41=red 42=green 43=yellow 44=blue 45=magenta 46=cyan 47=white
+
+#NORMAL 00 # no colour code at all
+FILE 00 # regular file: use no colour at all
+RESET 0 # reset to "normal" colour
+DIR 01;34 # directory
+LINK 01;36 # symbolic link; "target" colours it as the file it points to
+MULTIHARDLINK 00 # regular file with more than one link
+FIFO 40;33 # pipe
+SOCK 01;35 # socket
+DOOR 01;35 # door
+BLK 40;33;01 # block device driver
+CHR 40;33;01 # character device driver
+ORPHAN 40;31;01 # symlink to nonexistent file, or non-stat'able file
+MISSING 00 # ... and the files they point to
+SETUID 37;41 # file that is setuid (u+s)
+SETGID 30;43 # file that is setgid (g+s)
+CAPABILITY 30;41 # file with capability (security.capability xattr)
+STICKY_OTHER_WRITABLE 30;42 # dir that is sticky and other-writable (+t,o+w)
+OTHER_WRITABLE 34;42 # dir that is other-writable (o+w) and not sticky
+STICKY 37;44 # dir with the sticky bit set (+t) and not other-writable
+EXEC 01;32 # files with execute permission
+
+# Archives and compressed files (bold red)
+.tar 01;31
+.tgz 01;31
+.taz 01;31
+.tbz 01;31
+.tbz2 01;31
+.txz 01;31
+.tzst 01;31
+.gz 01;31
+.bz2 01;31
+.xz 01;31
+.lz 01;31
+.lzma 01;31
+.lz4 01;31
+.zst 01;31
+.Z 01;31
+.z 01;31
+.zip 01;31
+.jar 01;31
+.war 01;31
+.ear 01;31
+.rar 01;31
+.7z 01;31
+.cpio 01;31
+.deb 01;31
+.rpm 01;31
+.apk 01;31
+.iso 01;31
+.dmg 01;31
+
+# Images (bold magenta)
+.jpg 01;35
+.jpeg 01;35
+.JPG 01;35
+.png 01;35
+.gif 01;35
+.bmp 01;35
+.tif 01;35
+.tiff 01;35
+.webp 01;35
+.avif 01;35
+.heic 01;35
+.svg 01;35
+.svgz 01;35
+.ico 01;35
+.xcf 01;35
+.psd 01;35
+.pbm 01;35
+.pgm 01;35
+.ppm 01;35
+.xpm 01;35
+
+# Video (bold magenta, as in the coreutils database)
+.mp4 01;35
+.m4v 01;35
+.mkv 01;35
+.webm 01;35
+.mov 01;35
+.avi 01;35
+.wmv 01;35
+.flv 01;35
+.mpg 01;35
+.mpeg 01;35
+.ogv 01;35
+.3gp 01;35
+
+# Audio (cyan)
+.aac 00;36
+.flac 00;36
+.m4a 00;36
+.mid 00;36
+.midi 00;36
+.mp3 00;36
+.ogg 00;36
+.oga 00;36
+.opus 00;36
+.wav 00;36
+.wma 00;36
+
+# Documents (yellow)
+.pdf 00;33
+.epub 00;33
+.djvu 00;33
+.ps 00;33
+.doc 00;33
+.docx 00;33
+.odt 00;33
+.rtf 00;33
+.xls 00;33
+.xlsx 00;33
+.ods 00;33
+.csv 00;33
+.ppt 00;33
+.pptx 00;33
+.odp 00;33
+
+# Source code (green)
+.c 00;32
+.h 00;32
+.cc 00;32
+.cpp 00;32
+.hpp 00;32
+.go 00;32
+.rs 00;32
+.java 00;32
+.kt 00;32
+.scala 00;32
+.py 00;32
+.rb 00;32
+.pl 00;32
+.php 00;32
+.js 00;32
+.mjs 00;32
+.ts 00;32
+.tsx 00;32
+.jsx 00;32
+.swift 00;32
+.cs 00;32
+.hs 00;32
+.lua 00;32
+.sh 00;32
+.zig 00;32
+`
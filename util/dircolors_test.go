@@ -0,0 +1,118 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDircolors(t *testing.T) {
+	db := `# comment
+DIR 01;34 # trailing comment
+.md 33
+*README* 04
+
+TERM xterm*
+TERM screen
+EXEC 01;32
+COLORTERM ?*
+TERM linux
+LINK target
+OPTIONS -F
+eightbit 1
+*.go 32#not-a-comment
+`
+	tests := []struct {
+		name      string
+		term      string
+		colorterm string
+		want      string
+	}{
+		{
+			name: "no terminal matches",
+			term: "dumb",
+			want: "di=01;34:*.md=33:*README*=04:",
+		},
+		{
+			name: "first run matches",
+			term: "xterm-256color",
+			want: "di=01;34:*.md=33:*README*=04:ex=01;32:",
+		},
+		{
+			name:      "second run matches through COLORTERM",
+			term:      "dumb",
+			colorterm: "truecolor",
+			want:      "di=01;34:*.md=33:*README*=04:ln=target:*.go=32#not-a-comment:",
+		},
+		{
+			name: "second run matches through TERM",
+			term: "linux",
+			want: "di=01;34:*.md=33:*README*=04:ln=target:*.go=32#not-a-comment:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Dircolors(strings.NewReader(db), tt.term, tt.colorterm)
+			if err != nil {
+				t.Fatalf("Dircolors: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Dircolors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDircolors_Errors(t *testing.T) {
+	tests := []struct {
+		db   string
+		want string
+	}{
+		{db: "DIR 01\nPURPLE 35\n", want: "line 2: unrecognized keyword PURPLE"},
+		{db: "DIR\n", want: "line 1: missing argument for DIR"},
+	}
+	for _, tt := range tests {
+		_, err := Dircolors(strings.NewReader(tt.db), "xterm", "")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Dircolors(%q) error = %v, want %q", tt.db, err, tt.want)
+		}
+	}
+}
+
+func TestDircolorsDatabase(t *testing.T) {
+	spec, err := Dircolors(strings.NewReader(DircolorsDatabase), "xterm-256color", "")
+	if err != nil {
+		t.Fatalf("built-in database: %v", err)
+	}
+	p, err := ParseLSColors(spec)
+	if err != nil {
+		t.Fatalf("built-in database gives an unparsable LS_COLORS: %v", err)
+	}
+
+	// One file from each group the database covers
+	for file, want := range map[string]string{
+		"a.tar.gz": "01;31",
+		"a.png":    "01;35",
+		"a.mkv":    "01;35",
+		"a.flac":   "00;36",
+		"a.pdf":    "00;33",
+		"main.go":  "00;32",
+		"notes":    "00",
+	} {
+		if got := p.colour(0o644, file); got != want {
+			t.Errorf("built-in database colours %v as %q, want %q", file, got, want)
+		}
+	}
+
+	// The types agree with the colours used without LS_COLORS
+	def := DefaultPalette()
+	for _, key := range []string{"di", "ln", "pi", "so", "bd", "cd", "ex", "su", "sg", "ca", "st", "ow", "tw"} {
+		if p.keys[key] != def.keys[key] {
+			t.Errorf("database %v = %q, default palette has %q", key, p.keys[key], def.keys[key])
+		}
+	}
+
+	if spec, _ := Dircolors(strings.NewReader(DircolorsDatabase), "dumb", ""); spec != "" {
+		t.Errorf("built-in database should not colour a dumb terminal, got %q", spec)
+	}
+}
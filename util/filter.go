@@ -0,0 +1,349 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Entry is a single file as seen by the filters: its info plus the path it was reached by
+type Entry struct {
+	os.FileInfo
+	Path  string
+	Depth int // 0 for command-line operands, 1 for the contents of a listed directory, and so on
+}
+
+// Stat returns the raw stat data behind an entry
+func (e Entry) Stat() *syscall.Stat_t {
+	if st, ok := e.Sys().(*syscall.Stat_t); ok {
+		return st
+	}
+	st := getStat(e.Path)
+	return &st
+}
+
+// Predicate reports whether an entry should be kept in a listing
+type Predicate func(e Entry) bool
+
+// NewFilter builds the predicate for a find-style filter option such as
+// "type", "size" or "perm", parsing its value up front so bad input is
+// reported before anything is listed
+func NewFilter(name, value string) (Predicate, error) {
+	switch name {
+	case "type":
+		return typeFilter(value)
+	case "size":
+		return sizeFilter(value)
+	case "newer-than":
+		t, err := ParseTimeRef(value, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return func(e Entry) bool { return e.ModTime().After(t) }, nil
+	case "older-than":
+		t, err := ParseTimeRef(value, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return func(e Entry) bool { return e.ModTime().Before(t) }, nil
+	case "newer":
+		ref, err := os.Stat(value)
+		if err != nil {
+			// Name the file once, with the reason it could not be read
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				err = pathErr.Err
+			}
+			return nil, fmt.Errorf("cannot access '%v': %v", value, err)
+		}
+		t := ref.ModTime()
+		return func(e Entry) bool { return e.ModTime().After(t) }, nil
+	case "user":
+		match := idMatcher(value, func(name string) (uint32, bool) { return resolver.UserID(name) })
+		return func(e Entry) bool { return match(e.Stat().Uid) }, nil
+	case "group":
+		match := idMatcher(value, func(name string) (uint32, bool) { return resolver.GroupID(name) })
+		return func(e Entry) bool { return match(e.Stat().Gid) }, nil
+	case "perm":
+		return permFilter(value)
+	case "empty":
+		return isEmpty, nil
+	case "readable":
+		return accessFilter(readOK), nil
+	case "writable":
+		return accessFilter(writeOK), nil
+	case "executable":
+		return accessFilter(execOK), nil
+	}
+	return nil, fmt.Errorf("unknown filter '%v'", name)
+}
+
+// KeepFile reports whether e passes every filter in flag.Filters
+func KeepFile(e Entry, flag Flags) bool {
+	for _, p := range flag.Filters {
+		if !p(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// TypeLetter returns the find(1) type letter for a file mode
+func TypeLetter(mode os.FileMode) byte {
+	switch {
+	case mode.IsDir():
+		return 'd'
+	case mode&os.ModeSymlink != 0:
+		return 'l'
+	case mode&os.ModeNamedPipe != 0:
+		return 'p'
+	case mode&os.ModeSocket != 0:
+		return 's'
+	case mode&os.ModeCharDevice != 0:
+		return 'c'
+	case mode&os.ModeDevice != 0:
+		return 'b'
+	default:
+		return 'f'
+	}
+}
+
+func typeFilter(value string) (Predicate, error) {
+	want := map[byte]bool{}
+	for _, t := range strings.Split(value, ",") {
+		if len(t) != 1 || !strings.Contains("fdlpsbc", t) {
+			return nil, fmt.Errorf("invalid file type '%v'", t)
+		}
+		want[t[0]] = true
+	}
+	return func(e Entry) bool { return want[TypeLetter(e.Mode())] }, nil
+}
+
+// sizeUnits maps find(1) size suffixes to their size in bytes
+var sizeUnits = map[byte]int64{
+	'c': 1,
+	'k': 1 << 10,
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+	'P': 1 << 50,
+}
+
+// sizeFilter parses "+N", "-N" or "N" with an optional unit suffix. Like
+// find(1), sizes are rounded up to whole units before comparing, so "-1M"
+// only matches empty files
+func sizeFilter(value string) (Predicate, error) {
+	s := value
+	cmp := 0
+	if strings.HasPrefix(s, "+") {
+		cmp, s = 1, s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		cmp, s = -1, s[1:]
+	}
+	unit := int64(1)
+	if s != "" {
+		if u, ok := sizeUnits[s[len(s)-1]]; ok {
+			unit, s = u, s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid size '%v'", value)
+	}
+	return func(e Entry) bool {
+		units := (e.Size() + unit - 1) / unit
+		switch cmp {
+		case 1:
+			return units > n
+		case -1:
+			return units < n
+		}
+		return units == n
+	}, nil
+}
+
+// timeLayouts are the absolute date forms accepted by ParseTimeRef
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeRef turns an age such as "2d", "90m" or "1w" into the point that
+// long before now, or parses an absolute date in the local time zone
+func ParseTimeRef(value string, now time.Time) (time.Time, error) {
+	if d, err := parseAge(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%v'", value)
+}
+
+// parseAge parses a number followed by s, m, h, d or w
+func parseAge(value string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		's': time.Second,
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if value == "" {
+		return 0, fmt.Errorf("invalid age")
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid age '%v'", value)
+	}
+	n, err := strconv.ParseFloat(value[:len(value)-1], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age '%v'", value)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// idMatcher matches a numeric id or a user or group name. Names are
+// resolved on first use, once the run's resolver is in place; Print has
+// checked them with CheckIDNames by then, so an unknown one matching
+// nothing is only a fallback
+func idMatcher(value string, lookup func(string) (uint32, bool)) func(uint32) bool {
+	if id, ok := parseID(value); ok {
+		return func(n uint32) bool { return n == id }
+	}
+	var id uint32
+	var resolved, known bool
+	return func(n uint32) bool {
+		if !resolved {
+			id, known = lookup(value)
+			resolved = true
+		}
+		return known && n == id
+	}
+}
+
+// PermBits returns the permission bits of a mode, including setuid, setgid and sticky
+func PermBits(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= syscall.S_ISUID
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= syscall.S_ISGID
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= syscall.S_ISVTX
+	}
+	return bits
+}
+
+// permFilter follows find(1) -perm: "MODE" matches exactly, "-MODE" needs
+// every bit set and "/MODE" needs any of them. MODE is octal or symbolic
+func permFilter(value string) (Predicate, error) {
+	s := value
+	var kind byte
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "/") {
+		kind, s = s[0], s[1:]
+	}
+	want, err := parseMode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode '%v'", value)
+	}
+	return func(e Entry) bool {
+		bits := PermBits(e.Mode())
+		switch kind {
+		case '-':
+			return bits&want == want
+		case '/':
+			return want == 0 || bits&want != 0
+		}
+		return bits == want
+	}, nil
+}
+
+// parseMode parses an octal mode or a chmod-style symbolic mode such as
+// "u+w,o+w" applied to an empty mode
+func parseMode(s string) (uint32, error) {
+	if n, err := strconv.ParseUint(s, 8, 32); err == nil && n <= 0o7777 {
+		return uint32(n), nil
+	}
+	var mode uint32
+	for _, clause := range strings.Split(s, ",") {
+		i := 0
+		var who uint32
+		for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+			switch clause[i] {
+			case 'u':
+				who |= 0o4700
+			case 'g':
+				who |= 0o2070
+			case 'o':
+				who |= 0o1007
+			case 'a':
+				who |= 0o7777
+			}
+		}
+		if who == 0 {
+			who = 0o7777
+		}
+		if i >= len(clause) || strings.IndexByte("+-=", clause[i]) < 0 {
+			return 0, fmt.Errorf("invalid mode")
+		}
+		op := clause[i]
+		var perm uint32
+		for _, c := range clause[i+1:] {
+			switch c {
+			case 'r':
+				perm |= 0o444
+			case 'w':
+				perm |= 0o222
+			case 'x':
+				perm |= 0o111
+			case 's':
+				perm |= 0o6000
+			case 't':
+				perm |= 0o1000
+			default:
+				return 0, fmt.Errorf("invalid mode")
+			}
+		}
+		perm &= who
+		switch op {
+		case '+':
+			mode |= perm
+		case '-':
+			mode &^= perm
+		case '=':
+			mode = mode&^who | perm
+		}
+	}
+	return mode, nil
+}
+
+// isEmpty matches empty regular files and directories with no entries
+func isEmpty(e Entry) bool {
+	if e.Mode().IsRegular() {
+		return e.Size() == 0
+	}
+	if !e.IsDir() {
+		return false
+	}
+	dir, err := os.Open(e.Path)
+	if err != nil {
+		return false
+	}
+	defer dir.Close()
+	names, _ := dir.Readdirnames(1)
+	return len(names) == 0
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewFilter(t *testing.T) {
+	base := t.TempDir()
+
+	os.WriteFile(joinPath(base, "empty.txt"), nil, 0o644)
+	os.WriteFile(joinPath(base, "small.txt"), make([]byte, 100), 0o644)
+	os.WriteFile(joinPath(base, "big.bin"), make([]byte, 3<<20), 0o600)
+	os.WriteFile(joinPath(base, "shared.txt"), []byte("x"), 0o644)
+	os.Chmod(joinPath(base, "shared.txt"), 0o646)
+	os.Mkdir(joinPath(base, "emptydir"), 0o755)
+	os.Mkdir(joinPath(base, "fulldir"), 0o755)
+	os.WriteFile(joinPath(joinPath(base, "fulldir"), "f"), []byte("x"), 0o644)
+	os.Symlink("small.txt", joinPath(base, "link"))
+
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	os.Chtimes(joinPath(base, "small.txt"), old, old)
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "type", value: "d", want: []string{"emptydir", "fulldir"}},
+		{name: "type", value: "f,l", want: []string{"big.bin", "empty.txt", "link", "shared.txt", "small.txt"}},
+		{name: "size", value: "+1M", want: []string{"big.bin"}},
+		{name: "size", value: "-1k", want: []string{"empty.txt"}},
+		{name: "size", value: "100c", want: []string{"small.txt"}},
+		{name: "older-than", value: "2021-01-01", want: []string{"small.txt"}},
+		{name: "newer-than", value: "1d", want: []string{"big.bin", "emptydir", "empty.txt", "fulldir", "link", "shared.txt"}},
+		{name: "perm", value: "/o+w", want: []string{"link", "shared.txt"}},
+		{name: "perm", value: "600", want: []string{"big.bin"}},
+		{name: "perm", value: "-u+x", want: []string{"emptydir", "fulldir", "link"}},
+		{name: "empty", value: "", want: []string{"emptydir", "empty.txt"}},
+		{name: "executable", value: "", want: []string{"emptydir", "fulldir"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			p, err := NewFilter(tt.name, tt.value)
+			if err != nil {
+				t.Fatalf("NewFilter(%q, %q) error: %v", tt.name, tt.value, err)
+			}
+			got, err := ReadDirNames(base, Flags{Filters: []Predicate{p}})
+			if err != nil {
+				t.Fatalf("ReadDirNames() error: %v", err)
+			}
+			for i := range got {
+				got[i] = StripANSI(got[i])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilter_NewerError(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "file"), nil, 0o644)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: joinPath(base, "missing"), want: "no such file or directory"},
+		{value: joinPath(base, "file/below"), want: "not a directory"},
+	}
+	for _, tt := range tests {
+		_, err := NewFilter("newer", tt.value)
+		if want := "cannot access '" + tt.value + "': " + tt.want; err == nil || err.Error() != want {
+			t.Errorf("NewFilter(newer, %q) error = %v, want %q", tt.value, err, want)
+		}
+	}
+}
+
+func TestNewFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"type", "x"},
+		{"type", "fd"},
+		{"size", "+abc"},
+		{"size", "10Q"},
+		{"newer-than", "yesterday"},
+		{"older-than", "2026-13-01"},
+		{"newer", "/non/existent/file"},
+		{"perm", "/z+w"},
+		{"perm", "9999"},
+		{"bogus", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			if _, err := NewFilter(tt.name, tt.value); err == nil {
+				t.Errorf("NewFilter(%q, %q) expected an error", tt.name, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"755", 0o755},
+		{"4755", 0o4755},
+		{"o+w", 0o002},
+		{"u+rwx,g+rx", 0o750},
+		{"a+r", 0o444},
+		{"+x", 0o111},
+		{"u+s", 0o4000},
+		{"a=rw,o-w", 0o664},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMode(tt.in)
+		if err != nil {
+			t.Errorf("parseMode(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMode(%q) = %o, want %o", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilters_WithShowAllAndLong(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, ".hidden"), []byte("x"), 0o644)
+	os.Mkdir(joinPath(base, "dir"), 0o755)
+
+	p, _ := NewFilter("type", "f")
+	lines, err := ReadDirNamesLong(base, Flags{ShowAll: true, Filters: []Predicate{p}})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+
+	// total line plus .hidden only; . , .. and dir are directories
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %v", len(lines), lines)
+	}
+	if StripANSI(StripLong(lines[1])) != ".hidden" {
+		t.Errorf("expected .hidden, got %q", lines[1])
+	}
+}
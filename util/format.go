@@ -0,0 +1,32 @@
+package util
+
+import "fmt"
+
+// Format is how entries are laid out outside long format (--format)
+type Format int
+
+const (
+	FormatDefault      Format = iota // vertical on a terminal, single-column otherwise; Print resolves it
+	FormatSingleColumn               // one entry per line (-1)
+	FormatVertical                   // columns filled top to bottom (-C)
+	FormatAcross                     // columns filled left to right (-x)
+	FormatCommas                     // a comma-separated list filling each line (-m)
+)
+
+// formats are the --format names other than long and verbose, which turn
+// on long format instead
+var formats = map[string]Format{
+	"single-column": FormatSingleColumn,
+	"vertical":      FormatVertical,
+	"across":        FormatAcross,
+	"horizontal":    FormatAcross,
+	"commas":        FormatCommas,
+}
+
+// ParseFormat parses a --format argument other than long or verbose
+func ParseFormat(s string) (Format, error) {
+	if format, ok := formats[s]; ok {
+		return format, nil
+	}
+	return FormatDefault, fmt.Errorf("invalid argument '%v' for '--format' (valid: across, commas, horizontal, long, single-column, verbose, vertical)", s)
+}
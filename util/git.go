@@ -0,0 +1,430 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitStatuses returns the two-letter git status (as in git status --short)
+// of each entry of dir that has one, keyed by name. A directory gets the
+// statuses of what is below it combined. Outside a work tree there are
+// none.
+//
+// The statuses come from the index and the .gitignore files, without
+// running git: a tracked file whose size, modification time or type no
+// longer match what the index recorded is modified (" M"), a missing one
+// deleted (" D"), a file the index does not know untracked ("??") or
+// ignored ("!!"). Comparing the index with HEAD would mean reading
+// compressed objects, so changes staged for commit are not shown
+func gitStatuses(dir string) map[string]string {
+	top, gitDir, ok := findWorkTree(dir)
+	if !ok {
+		return nil
+	}
+	prefix, err := filepath.Rel(top, resolvedPath(dir))
+	if err != nil || prefix == ".git" || strings.HasPrefix(prefix, ".git/") {
+		return nil
+	}
+	if prefix == "." {
+		prefix = ""
+	} else {
+		prefix = filepath.ToSlash(prefix) + "/"
+	}
+
+	index, err := readGitIndex(joinPath(gitDir, "index"), gitHashSize(gitDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	statuses := map[string]string{}
+	add := func(path, status string) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+		statuses[name] = mergeGitStatus(statuses[name], status)
+	}
+
+	// Tracked files that changed, and the directories that hold tracked
+	// files, which are the ones to look through for untracked files
+	tracked := map[string]bool{}
+	for _, entry := range index {
+		if !strings.HasPrefix(entry.path, prefix) {
+			continue
+		}
+		tracked[entry.path] = true
+		for d := entry.path; strings.Contains(d, "/"); {
+			d = d[:strings.LastIndex(d, "/")]
+			tracked[d+"/"] = true
+		}
+		if status := entry.status(top); status != "" {
+			add(entry.path, status)
+		}
+	}
+
+	ignore := &gitIgnore{}
+	ignore.load(joinPath(gitDir, "info/exclude"), "")
+	ignore.load(joinPath(top, ".gitignore"), "")
+	for d := ""; d != prefix; {
+		next, _, _ := strings.Cut(prefix[len(d):], "/")
+		d += next + "/"
+		ignore.load(joinPath(top, d+".gitignore"), d)
+	}
+
+	var walk func(rel string, ignored bool)
+	walk = func(rel string, ignored bool) {
+		entries, err := os.ReadDir(joinPath(top, rel))
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			path := rel + e.Name()
+			if path == ".git" {
+				continue
+			}
+			isDir := e.IsDir()
+			pathIgnored := ignored || ignore.match(path, isDir)
+			switch {
+			case tracked[path]:
+			case isDir && (tracked[path+"/"] || !pathIgnored):
+				// Untracked directories are searched too, since git
+				// leaves out those holding nothing but ignored files
+				ignore.load(joinPath(top, path+"/.gitignore"), path+"/")
+				walk(path+"/", pathIgnored)
+			case pathIgnored:
+				add(path, "!!")
+			default:
+				add(path, "??")
+			}
+		}
+	}
+	walk(prefix, prefix != "" && ignore.match(strings.TrimSuffix(prefix, "/"), true))
+	return statuses
+}
+
+// findWorkTree looks in dir and each directory above it for .git, either
+// the repository itself or a file naming it, as in linked work trees and
+// submodules
+func findWorkTree(dir string) (top, gitDir string, ok bool) {
+	for current := resolvedPath(dir); ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return current, dotGit, true
+			}
+			data, err := os.ReadFile(dotGit)
+			if target, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: "); err == nil && found {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(current, target)
+				}
+				return current, target, true
+			}
+		}
+		if current == filepath.Dir(current) {
+			return "", "", false
+		}
+	}
+}
+
+// resolvedPath is the absolute path of dir with symlinks followed, as git
+// sees the current directory
+func resolvedPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// gitHashSize is the length of an object name in the repository: 20 bytes
+// of SHA-1, or 32 of SHA-256 when the config asks for it
+func gitHashSize(gitDir string) int {
+	config, _ := os.ReadFile(joinPath(gitDir, "config"))
+	if strings.Contains(strings.NewReplacer(" ", "", "\t", "").Replace(string(config)), "objectformat=sha256") {
+		return 32
+	}
+	return 20
+}
+
+// gitIndexEntry is what the index records of a tracked file
+type gitIndexEntry struct {
+	path         string
+	mtime        int64
+	mtimeNsec    int64
+	mode         uint32
+	size         uint32
+	stage        int
+	assumeIntact bool // assume-unchanged or skip-worktree
+}
+
+const gitLinkMode = 0o160000 // a submodule
+
+// status compares the entry with the file in the work tree at top
+func (e gitIndexEntry) status(top string) string {
+	switch {
+	case e.stage != 0:
+		return "UU"
+	case e.assumeIntact || e.mode == gitLinkMode:
+		return ""
+	}
+	info, err := os.Lstat(joinPath(top, e.path))
+	if err != nil {
+		return " D"
+	}
+	mode, mtime := info.Mode(), info.ModTime()
+	isLink := mode&os.ModeSymlink != 0
+	switch {
+	case isLink != (e.mode&0o170000 == 0o120000), mode.IsDir():
+		return " T"
+	case !isLink && uint32(mode)&0o100 != e.mode&0o100:
+		return " M"
+	case uint32(info.Size()) != e.size, mtime.Unix() != e.mtime, e.mtimeNsec != 0 && int64(mtime.Nanosecond()) != e.mtimeNsec:
+		return " M"
+	}
+	return ""
+}
+
+var errBadIndex = errors.New("not a git index")
+
+// readGitIndex parses an index file of version 2, 3 or 4
+func readGitIndex(path string, hashSize int) ([]gitIndexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, errBadIndex
+	}
+	version, count := bigEndian32(data[4:]), bigEndian32(data[8:])
+	if version < 2 || version > 4 {
+		return nil, errBadIndex
+	}
+
+	entries := make([]gitIndexEntry, 0, min(count, uint32(len(data)/62)))
+	pos, previous := 12, ""
+	for range count {
+		start := pos
+		fixed := 40 + hashSize + 2
+		if pos+fixed > len(data) {
+			return nil, errBadIndex
+		}
+		flags := uint16(data[pos+fixed-2])<<8 | uint16(data[pos+fixed-1])
+		e := gitIndexEntry{
+			mtime:        int64(bigEndian32(data[pos+8:])),
+			mtimeNsec:    int64(bigEndian32(data[pos+12:])),
+			mode:         bigEndian32(data[pos+24:]),
+			size:         bigEndian32(data[pos+36:]),
+			stage:        int(flags>>12) & 3,
+			assumeIntact: flags&0x8000 != 0,
+		}
+		pos += fixed
+		if version >= 3 && flags&0x4000 != 0 {
+			if pos+2 > len(data) {
+				return nil, errBadIndex
+			}
+			e.assumeIntact = e.assumeIntact || data[pos]&0x40 != 0 // skip-worktree
+			pos += 2
+		}
+
+		if version == 4 {
+			// The path is given as how much to drop from the end of the
+			// previous one, then what to append
+			strip, n := gitVarint(data[pos:])
+			if n == 0 || strip > uint64(len(previous)) {
+				return nil, errBadIndex
+			}
+			pos += n
+			end := pos
+			for end < len(data) && data[end] != 0 {
+				end++
+			}
+			if end == len(data) {
+				return nil, errBadIndex
+			}
+			e.path = previous[:len(previous)-int(strip)] + string(data[pos:end])
+			pos = end + 1
+		} else {
+			end := pos
+			for end < len(data) && data[end] != 0 {
+				end++
+			}
+			if end == len(data) {
+				return nil, errBadIndex
+			}
+			e.path = string(data[pos:end])
+			// Entries are padded with NULs to a multiple of eight bytes
+			pos = start + (end-start+8)&^7
+		}
+		previous = e.path
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func bigEndian32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// gitVarint decodes the offset encoding of index version 4, in which each
+// continuation byte also adds one, returning the value and its length
+func gitVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	value := uint64(b[0] & 0x7f)
+	n := 1
+	for b[n-1]&0x80 != 0 {
+		if n == len(b) {
+			return 0, 0
+		}
+		value = (value+1)<<7 | uint64(b[n]&0x7f)
+		n++
+	}
+	return value, n
+}
+
+// gitIgnore holds the patterns of the .gitignore files read so far
+type gitIgnore struct {
+	rules []ignoreRule
+}
+
+// ignoreRule is one pattern, applying below base
+type ignoreRule struct {
+	base     string // the directory of the .gitignore, "" or ending in /
+	re       *regexp.Regexp
+	anchored bool // matched against the path below base, not just the name
+	negate   bool
+	dirOnly  bool
+}
+
+// load adds the patterns of the ignore file at path, if there is one,
+// for paths below base
+func (g *gitIgnore) load(path, base string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		} else if line[0] == '\\' && len(line) > 1 && (line[1] == '#' || line[1] == '!') {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.anchored = strings.Contains(line, "/")
+		re, err := regexp.Compile("^" + globRegexp(strings.TrimPrefix(line, "/")) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		g.rules = append(g.rules, rule)
+	}
+}
+
+// match reports whether path, relative to the top of the work tree, is
+// ignored. The last pattern that matches decides
+func (g *gitIgnore) match(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir || !strings.HasPrefix(path, rule.base) {
+			continue
+		}
+		target := path[len(rule.base):]
+		if !rule.anchored {
+			target = target[strings.LastIndex(target, "/")+1:]
+		}
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globRegexp translates a gitignore glob into a regular expression: *
+// and ? stay within one path component, while ** spans several
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// mergeGitStatus combines the statuses of two files into that of the
+// directory holding them. Changes to tracked files outrank untracked ones,
+// which outrank ignored ones; between two changes each side shows a change
+// if either file has one
+func mergeGitStatus(a, b string) string {
+	ra, rb := gitRank(a), gitRank(b)
+	switch {
+	case ra < rb:
+		return b
+	case ra > rb || a == b:
+		return a
+	}
+	merged := []byte(a)
+	for i := range merged {
+		if merged[i] == ' ' {
+			merged[i] = b[i]
+		}
+	}
+	return string(merged)
+}
+
+// gitRank orders statuses for mergeGitStatus: none, ignored, untracked,
+// then any change to a tracked file
+func gitRank(status string) int {
+	switch status {
+	case "":
+		return 0
+	case "!!":
+		return 1
+	case "??":
+		return 2
+	}
+	return 3
+}
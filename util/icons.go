@@ -0,0 +1,237 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IconMode is the --icons setting
+type IconMode int
+
+const (
+	IconsNever  IconMode = iota // no icons, the default
+	IconsAlways                 // icons even into pipes and files
+	IconsAuto                   // icons only when writing to a terminal
+)
+
+// ParseIconMode parses an --icons argument, with the same spellings as
+// --color. An empty value, from a bare --icons, means always
+func ParseIconMode(s string) (IconMode, error) {
+	switch s {
+	case "", "always", "yes", "force":
+		return IconsAlways, nil
+	case "never", "no", "none":
+		return IconsNever, nil
+	case "auto", "tty", "if-tty":
+		return IconsAuto, nil
+	}
+	return IconsNever, fmt.Errorf("invalid argument '%v' for '--icons' (valid: always, never, auto)", s)
+}
+
+// IconSet holds the Nerd Font glyphs put before names: by exact file name,
+// by extension for files, and by the LS_COLORS key of the file's type
+type IconSet struct {
+	names      map[string]string
+	extensions map[string]string // lower case, with the leading dot
+	types      map[string]string
+}
+
+// defaultTypeIcons are the icons for each kind of file, keyed like typeKey
+var defaultTypeIcons = map[string]string{
+	"fi": "\uf15b", // file
+	"di": "\uf115", // folder open
+	"ln": "\uf0c1", // link
+	"or": "\uf127", // broken link
+	"ex": "\uf489", // terminal
+	"pi": "\uf07e", // arrows
+	"so": "\uf1e6", // plug
+	"do": "\uf1e6",
+	"bd": "\uf0a0", // hard disk
+	"cd": "\uf11c", // keyboard
+	"no": "\uf15b",
+}
+
+// defaultNameIcons are the icons for well-known file and directory names
+var defaultNameIcons = map[string]string{
+	"Makefile":           "\uf0ad", // wrench
+	"makefile":           "\uf0ad",
+	"GNUmakefile":        "\uf0ad",
+	"CMakeLists.txt":     "\uf0ad",
+	"Dockerfile":         "\uf308", // docker
+	"Containerfile":      "\uf308",
+	"docker-compose.yml": "\uf308",
+	"compose.yaml":       "\uf308",
+	".dockerignore":      "\uf308",
+	"go.mod":             "\ue627", // go
+	"go.sum":             "\ue627",
+	"go.work":            "\ue627",
+	"Cargo.toml":         "\ue7a8", // rust
+	"Cargo.lock":         "\ue7a8",
+	"package.json":       "\ue71e", // npm
+	"package-lock.json":  "\ue71e",
+	".git":               "\uf1d3", // git
+	".gitignore":         "\uf1d3",
+	".gitattributes":     "\uf1d3",
+	".gitmodules":        "\uf1d3",
+	".github":            "\uf408", // octoface
+	"LICENSE":            "\ue60a",
+	"README":             "\uf48a",
+	"README.md":          "\uf48a",
+	"node_modules":       "\ue718", // node
+}
+
+// defaultExtensionIcons are the icons for common extensions
+var defaultExtensionIcons = map[string]string{
+	".go":   "\ue627",
+	".rs":   "\ue7a8",
+	".py":   "\ue606",
+	".rb":   "\ue21e",
+	".js":   "\ue74e",
+	".mjs":  "\ue74e",
+	".ts":   "\ue628",
+	".c":    "\ue61e",
+	".h":    "\uf0fd",
+	".cc":   "\ue61d",
+	".cpp":  "\ue61d",
+	".java": "\ue738",
+	".lua":  "\ue620",
+	".php":  "\ue608",
+	".sh":   "\uf489",
+	".bash": "\uf489",
+	".zsh":  "\uf489",
+	".vim":  "\ue62b",
+	".html": "\uf13b",
+	".css":  "\ue749",
+	".json": "\ue60b",
+	".toml": "\ue615",
+	".yml":  "\ue615",
+	".yaml": "\ue615",
+	".ini":  "\ue615",
+	".conf": "\ue615",
+	".md":   "\uf48a",
+	".txt":  "\uf15c",
+	".log":  "\uf15c",
+	".pdf":  "\uf1c1",
+	".lock": "\uf023",
+	".tar":  "\uf410",
+	".gz":   "\uf410",
+	".tgz":  "\uf410",
+	".bz2":  "\uf410",
+	".xz":   "\uf410",
+	".zst":  "\uf410",
+	".zip":  "\uf410",
+	".7z":   "\uf410",
+	".rar":  "\uf410",
+	".deb":  "\uf410",
+	".rpm":  "\uf410",
+	".jpg":  "\uf1c5",
+	".jpeg": "\uf1c5",
+	".png":  "\uf1c5",
+	".gif":  "\uf1c5",
+	".svg":  "\uf1c5",
+	".webp": "\uf1c5",
+	".mp3":  "\uf001",
+	".flac": "\uf001",
+	".ogg":  "\uf001",
+	".wav":  "\uf001",
+	".mp4":  "\uf03d",
+	".mkv":  "\uf03d",
+	".webm": "\uf03d",
+	".mov":  "\uf03d",
+}
+
+// DefaultIcons returns the built-in icon table
+func DefaultIcons() *IconSet {
+	s := &IconSet{names: map[string]string{}, extensions: map[string]string{}, types: map[string]string{}}
+	for name, icon := range defaultNameIcons {
+		s.names[name] = icon
+	}
+	for ext, icon := range defaultExtensionIcons {
+		s.extensions[ext] = icon
+	}
+	for key, icon := range defaultTypeIcons {
+		s.types[key] = icon
+	}
+	return s
+}
+
+// SetName sets the icon for files and directories called name
+func (s *IconSet) SetName(name, icon string) {
+	s.names[name] = icon
+}
+
+// SetExtension sets the icon for files with the extension ext, matched
+// case-insensitively; the leading dot is optional
+func (s *IconSet) SetExtension(ext, icon string) {
+	s.extensions["."+strings.ToLower(strings.TrimPrefix(ext, "."))] = icon
+}
+
+// SetType sets the icon for a kind of file, given by its LS_COLORS key
+// such as "di"
+func (s *IconSet) SetType(key, icon string) error {
+	if _, known := defaultTypeIcons[key]; !known {
+		return fmt.Errorf("no icon for colour key '%v'", key)
+	}
+	s.types[key] = icon
+	return nil
+}
+
+// icon returns the glyph for the file at path described by info: by its
+// name, then for files by its extension, and failing those by its type.
+// Links whose target is missing get the broken-link icon
+func (s *IconSet) icon(info os.FileInfo, path, root string) string {
+	key := typeKey(info.Mode())
+	if key == "ln" {
+		if resolved, err := ResolveLink(path, root); err != nil {
+			key = "or"
+		} else if _, err := os.Lstat(resolved); err != nil {
+			key = "or"
+		}
+		return s.types[key]
+	}
+
+	name := info.Name()
+	if icon, ok := s.names[name]; ok && (key == "fi" || key == "ex" || key == "di") {
+		return icon
+	}
+	if key == "fi" || key == "ex" {
+		if ext := Extension(name); ext != "" {
+			if icon, ok := s.extensions["."+strings.ToLower(ext)]; ok {
+				return icon
+			}
+		}
+	}
+	return s.types[key]
+}
+
+// Extension returns the part of name after its last dot, without the dot.
+// Dot files such as .bashrc have no extension
+func Extension(name string) string {
+	i := strings.LastIndexByte(name, '.')
+	if i <= 0 {
+		return ""
+	}
+	return name[i+1:]
+}
+
+// iconPrefix returns the icon and a space to put before a name, painted in
+// the name's colour, or nothing without --icons
+func iconPrefix(flag Flags, colour string, info os.FileInfo, path string) string {
+	if !flag.ShowIcons {
+		return ""
+	}
+	icon := icons.icon(info, path, flag.Root)
+	if icon == "" {
+		return ""
+	}
+	return paint(flag, colour, icon) + " "
+}
+
+// icons is the icon table for this run
+var icons = DefaultIcons()
+
+// SetIcons makes s the icons put before names
+func SetIcons(s *IconSet) {
+	icons = s
+}
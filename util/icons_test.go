@@ -0,0 +1,129 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIconMode(t *testing.T) {
+	for value, want := range map[string]IconMode{
+		"": IconsAlways, "always": IconsAlways, "never": IconsNever, "none": IconsNever, "auto": IconsAuto, "tty": IconsAuto,
+	} {
+		if got, err := ParseIconMode(value); err != nil || got != want {
+			t.Errorf("ParseIconMode(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseIconMode("sometimes"); err == nil {
+		t.Errorf("ParseIconMode(\"sometimes\") expected an error")
+	}
+}
+
+func TestIconSet_Icon(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Makefile"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "main.GO"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "run.sh"), nil, 0o755)
+	os.WriteFile(filepath.Join(dir, "tool"), nil, 0o755)
+	os.WriteFile(filepath.Join(dir, "notes"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "src"), 0o755)
+	os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+	os.Symlink("notes", filepath.Join(dir, "link"))
+	os.Symlink("missing", filepath.Join(dir, "broken"))
+
+	s := DefaultIcons()
+	tests := map[string]string{
+		"Makefile":   defaultNameIcons["Makefile"],
+		"go.mod":     defaultNameIcons["go.mod"],
+		"main.GO":    defaultExtensionIcons[".go"],
+		"run.sh":     defaultExtensionIcons[".sh"],
+		"tool":       defaultTypeIcons["ex"],
+		"notes":      defaultTypeIcons["fi"],
+		".gitignore": defaultNameIcons[".gitignore"],
+		".hidden":    defaultTypeIcons["fi"],
+		"src":        defaultTypeIcons["di"],
+		".git":       defaultNameIcons[".git"],
+		"link":       defaultTypeIcons["ln"],
+		"broken":     defaultTypeIcons["or"],
+	}
+	for name, want := range tests {
+		path := filepath.Join(dir, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.icon(info, path, ""); got != want {
+			t.Errorf("icon(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestIconSet_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Justfile"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "a.go"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "src"), 0o755)
+
+	s := DefaultIcons()
+	s.SetName("Justfile", "J")
+	s.SetExtension("GO", "G")
+	if err := s.SetType("di", "D"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetType("su", "S"); err == nil {
+		t.Errorf("SetType(\"su\") expected an error")
+	}
+
+	for name, want := range map[string]string{"Justfile": "J", "a.go": "G", "src": "D"} {
+		path := filepath.Join(dir, name)
+		info, _ := os.Lstat(path)
+		if got := s.icon(info, path, ""); got != want {
+			t.Errorf("icon(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestReadDirNames_Icons(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "src"), 0o755)
+
+	names, err := ReadDirNames(dir, Flags{ShowIcons: true, NoColor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{defaultNameIcons["go.mod"] + " go.mod", defaultTypeIcons["di"] + " src"}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("ReadDirNames() = %q, want %q", names, want)
+	}
+
+	names, _ = ReadDirNames(dir, Flags{ShowIcons: true})
+	if want := dirColour + defaultTypeIcons["di"] + reset + " " + dirColour + "src" + reset; names[1] != want {
+		t.Errorf("coloured icon = %q, want %q", names[1], want)
+	}
+
+	lines, _ := ReadDirNamesLong(dir, Flags{ShowIcons: true, NoColor: true})
+	if want := defaultTypeIcons["di"] + " src"; len(lines) != 3 || !strings.HasSuffix(lines[2], want) {
+		t.Errorf("long line = %q, want it to end in %q", lines[2], want)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := map[string]string{
+		"main.go":     "go",
+		"a.tar.gz":    "gz",
+		".bashrc":     "",
+		"Makefile":    "",
+		"trailing.":   "",
+		".config.yml": "yml",
+	}
+	for name, want := range tests {
+		if got := Extension(name); got != want {
+			t.Errorf("Extension(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
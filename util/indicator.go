@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"os"
+)
+
+// IndicatorStyle is which type indicators follow names (--indicator-style)
+type IndicatorStyle int
+
+const (
+	IndicatorNone     IndicatorStyle = iota // no indicators
+	IndicatorSlash                          // '/' after directories (-p)
+	IndicatorFileType                       // '/', '@', '|' and '=' (--file-type)
+	IndicatorClassify                       // those and '*' after executables (-F)
+)
+
+// ParseIndicatorStyle parses an --indicator-style argument
+func ParseIndicatorStyle(s string) (IndicatorStyle, error) {
+	switch s {
+	case "none":
+		return IndicatorNone, nil
+	case "slash":
+		return IndicatorSlash, nil
+	case "file-type":
+		return IndicatorFileType, nil
+	case "classify":
+		return IndicatorClassify, nil
+	}
+	return IndicatorNone, fmt.Errorf("invalid argument '%v' for '--indicator-style' (valid: none, slash, file-type, classify)", s)
+}
+
+// TypeIndicator returns the character to put after a name of the given mode,
+// or nothing
+func TypeIndicator(mode os.FileMode, style IndicatorStyle) string {
+	switch {
+	case style == IndicatorNone:
+		return ""
+	case mode.IsDir():
+		return "/"
+	case style == IndicatorSlash:
+		return ""
+	case mode&os.ModeSymlink != 0:
+		return "@"
+	case mode&os.ModeNamedPipe != 0:
+		return "|"
+	case mode&os.ModeSocket != 0:
+		return "="
+	case style == IndicatorClassify && mode.IsRegular() && mode&0o111 != 0:
+		return "*"
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestTypeIndicator(t *testing.T) {
+	modes := []os.FileMode{os.ModeDir | 0o755, os.ModeSymlink | 0o777, os.ModeNamedPipe | 0o644, os.ModeSocket | 0o755, 0o755, 0o644}
+	tests := []struct {
+		style IndicatorStyle
+		want  string
+	}{
+		{IndicatorNone, ""},
+		{IndicatorSlash, "/"},
+		{IndicatorFileType, "/@|="},
+		{IndicatorClassify, "/@|=*"},
+	}
+	for _, tt := range tests {
+		var got strings.Builder
+		for _, mode := range modes {
+			got.WriteString(TypeIndicator(mode, tt.style))
+		}
+		if got.String() != tt.want {
+			t.Errorf("indicators for style %v = %q, want %q", tt.style, got.String(), tt.want)
+		}
+	}
+}
+
+func TestParseIndicatorStyle(t *testing.T) {
+	for name, want := range map[string]IndicatorStyle{"none": IndicatorNone, "slash": IndicatorSlash, "file-type": IndicatorFileType, "classify": IndicatorClassify} {
+		if got, err := ParseIndicatorStyle(name); err != nil || got != want {
+			t.Errorf("ParseIndicatorStyle(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseIndicatorStyle("all"); err == nil {
+		t.Errorf("ParseIndicatorStyle(\"all\") expected an error")
+	}
+}
+
+func TestReadDirNames_Indicators(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "dir"), 0o755)
+	os.WriteFile(filepath.Join(dir, "run"), nil, 0o755)
+	os.WriteFile(filepath.Join(dir, "text"), nil, 0o644)
+	os.Symlink("dir", filepath.Join(dir, "link"))
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe"), 0o644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+
+	names, err := ReadDirNames(dir, Flags{NoColor: true, Indicator: IndicatorClassify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(names, " "), "dir/ link@ pipe| run* text"; got != want {
+		t.Errorf("-F names = %q, want %q", got, want)
+	}
+
+	// The indicator stays outside the colour
+	names, _ = ReadDirNames(dir, Flags{Indicator: IndicatorSlash})
+	if want := dirColour + "dir" + reset + "/"; names[0] != want {
+		t.Errorf("-p coloured name = %q, want %q", names[0], want)
+	}
+
+	// In long listings links show the indicator of their target instead
+	lines, _ := ReadDirNamesLong(dir, Flags{NoColor: true, Indicator: IndicatorClassify})
+	for _, want := range []string{" dir/", " link -> dir/", " run*"} {
+		if !hasSuffixLine(lines, want) {
+			t.Errorf("long lines = %q, want one ending in %q", lines, want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Palette holds the colours names are painted in, in the form LS_COLORS
+// gives them: SGR parameters such as "01;34" keyed by file class, plus
+// colours for names matching *.ext suffixes or globs
+type Palette struct {
+	keys     map[string]string
+	patterns []colorPattern
+	columns  map[string]string // long-format column name to colour, for theme files
+}
+
+// colorPattern is one LS_COLORS *.ext or glob entry
+type colorPattern struct {
+	suffix string // matched against the end of the name, for plain *suffix entries
+	glob   string // matched against the whole name, for entries with other wildcards
+	colour string
+}
+
+// colorKeys are the two-letter LS_COLORS keys my-ls understands, with
+// what they colour
+var colorKeys = map[string]string{
+	"no": "normal text",
+	"fi": "regular file",
+	"di": "directory",
+	"ln": "symbolic link, or \"target\" to colour links as what they point to",
+	"or": "symbolic link to a missing file",
+	"mi": "missing file a symbolic link points to",
+	"pi": "named pipe",
+	"so": "socket",
+	"do": "door",
+	"bd": "block device",
+	"cd": "character device",
+	"ex": "executable file",
+	"su": "setuid file",
+	"sg": "setgid file",
+	"st": "sticky directory",
+	"ow": "other-writable directory",
+	"tw": "sticky, other-writable directory",
+	"ca": "file with capabilities",
+	"mh": "file with multiple hard links",
+	"rs": "reset sequence",
+	"lc": "left of a colour sequence",
+	"rc": "right of a colour sequence",
+	"ec": "end of a coloured name, replacing lc rs rc",
+	"cl": "clear to end of line",
+}
+
+// defaultColorKeys are the colours used for keys LS_COLORS leaves unset
+var defaultColorKeys = map[string]string{
+	"fi": "0",
+	"di": "01;34",
+	"ln": "01;36",
+	"pi": "40;33",
+	"so": "01;35",
+	"bd": "40;33;01",
+	"cd": "40;33;01",
+	"ex": "01;32",
+	"su": "37;41",
+	"sg": "30;43",
+	"ca": "30;41",
+	"st": "37;44",
+	"ow": "34;42",
+	"tw": "30;42",
+	"rs": "0",
+	"lc": "\033[",
+	"rc": "m",
+}
+
+// defaultArchives are the suffixes coloured as archives when LS_COLORS is not set
+var defaultArchives = []string{".tar", ".gz", ".tgz", ".zip", ".bz2", ".xz"}
+
+// DefaultPalette returns the colours used when LS_COLORS is not set
+func DefaultPalette() *Palette {
+	p := &Palette{keys: map[string]string{}}
+	for key, colour := range defaultColorKeys {
+		p.keys[key] = colour
+	}
+	for _, suffix := range defaultArchives {
+		p.patterns = append(p.patterns, colorPattern{suffix: suffix, colour: "01;31"})
+	}
+	return p
+}
+
+// ParseLSColors parses an LS_COLORS value such as "di=01;34:*.tar=01;31".
+// Keys it leaves unset keep their defaults, but its *.ext and glob entries
+// replace the built-in archive suffixes. Values may use the same escapes
+// as dircolors: \e, \NNN octal, \xHH and ^X among them
+func ParseLSColors(spec string) (*Palette, error) {
+	p := DefaultPalette()
+	p.patterns = nil
+
+	for _, entry := range strings.Split(spec, ":") {
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid LS_COLORS entry '%v': missing '='", entry)
+		}
+		colour, err := unescapeColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LS_COLORS entry '%v': %v", entry, err)
+		}
+
+		if strings.HasPrefix(key, "*") {
+			if err := p.SetPattern(key, colour); err != nil {
+				return nil, fmt.Errorf("invalid LS_COLORS entry '%v': %v", entry, err)
+			}
+			continue
+		}
+		unescaped, err := unescapeColor(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LS_COLORS key '%v': %v", key, err)
+		}
+		if _, known := colorKeys[unescaped]; !known {
+			return nil, fmt.Errorf("unrecognized LS_COLORS key '%v'", key)
+		}
+		p.keys[unescaped] = colour
+	}
+	return p, nil
+}
+
+// unescapeColor expands the backslash and caret escapes dircolors allows
+func unescapeColor(s string) (string, error) {
+	if !strings.ContainsAny(s, `\^`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '^' && i+1 < len(s):
+			i++
+			if s[i] == '?' {
+				b.WriteByte(0x7f)
+			} else {
+				b.WriteByte(upper(s[i]) & 0x1f)
+			}
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'e':
+				b.WriteByte(0x1b)
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'v':
+				b.WriteByte('\v')
+			case '?':
+				b.WriteByte(0x7f)
+			case '_':
+				b.WriteByte(' ')
+			case 'x', 'X':
+				j := i + 1
+				for j < len(s) && j < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+					j++
+				}
+				if j == i+1 {
+					return "", fmt.Errorf("\\x needs hex digits")
+				}
+				n, _ := strconv.ParseUint(s[i+1:j], 16, 8)
+				b.WriteByte(byte(n))
+				i = j - 1
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				j := i
+				for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+					j++
+				}
+				n, _ := strconv.ParseUint(s[i:j], 8, 16)
+				b.WriteByte(byte(n))
+				i = j - 1
+			default:
+				b.WriteByte(e)
+			}
+		case c == '\\' || c == '^':
+			return "", fmt.Errorf("dangling '%c'", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// typeKey returns the LS_COLORS key for the kind of file mode describes
+func typeKey(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "di"
+	case mode&os.ModeSymlink != 0:
+		return "ln"
+	case mode&os.ModeSocket != 0:
+		return "so"
+	case mode&os.ModeNamedPipe != 0:
+		return "pi"
+	case mode&os.ModeCharDevice != 0:
+		return "cd"
+	case mode&os.ModeDevice != 0:
+		return "bd"
+	case mode&0o111 != 0:
+		return "ex"
+	case mode.IsRegular():
+		return "fi"
+	}
+	return "no"
+}
+
+// colour returns the SGR parameters for a file of the given mode and name,
+// for when nothing but the mode is known
+func (p *Palette) colour(mode os.FileMode, name string) string {
+	return p.keyColour(p.fileKey(mode, nil, ""), name)
+}
+
+// fileKey refines typeKey with the permission classes: setuid and setgid
+// files, files with capabilities or several hard links, and sticky or
+// other-writable directories. A class is only chosen when it has a colour,
+// and the link count and capabilities, which need info and path, are only
+// looked up then
+func (p *Palette) fileKey(mode os.FileMode, info os.FileInfo, path string) string {
+	key := typeKey(mode)
+	switch key {
+	case "ex", "fi":
+		switch {
+		case mode&os.ModeSetuid != 0 && p.isColored("su"):
+			return "su"
+		case mode&os.ModeSetgid != 0 && p.isColored("sg"):
+			return "sg"
+		case p.isColored("ca") && path != "" && HasCapability(path):
+			return "ca"
+		case key == "ex" && p.isColored("ex"):
+			return "ex"
+		case p.isColored("mh") && info != nil && (Entry{FileInfo: info, Path: path}).Stat().Nlink > 1:
+			return "mh"
+		}
+		return "fi"
+	case "di":
+		sticky, otherWritable := mode&os.ModeSticky != 0, mode&0o002 != 0
+		switch {
+		case sticky && otherWritable && p.isColored("tw"):
+			return "tw"
+		case otherWritable && p.isColored("ow"):
+			return "ow"
+		case sticky && p.isColored("st"):
+			return "st"
+		}
+	}
+	return key
+}
+
+// isColored reports whether key has a colour of its own. As in GNU ls, "0"
+// and "00", which dircolors gives keys such as MULTIHARDLINK to turn them
+// off, count as none
+func (p *Palette) isColored(key string) bool {
+	colour := p.keys[key]
+	return colour != "" && colour != "0" && colour != "00"
+}
+
+// keyColour returns the colour for key. Suffixes and globs only apply to
+// files with no more specific class, and the last matching entry wins
+func (p *Palette) keyColour(key, name string) string {
+	if key == "fi" {
+		for i := len(p.patterns) - 1; i >= 0; i-- {
+			if p.patterns[i].matches(name) {
+				return p.patterns[i].colour
+			}
+		}
+	}
+	if colour := p.keys[key]; colour != "" || key == "no" {
+		return colour
+	}
+	return p.keys["no"]
+}
+
+func (c colorPattern) matches(name string) bool {
+	if c.glob != "" {
+		ok, _ := path.Match(c.glob, name)
+		return ok
+	}
+	return strings.HasSuffix(name, c.suffix)
+}
+
+// entryColour returns the colour for the file at path described by info.
+// Links whose target is missing use "or", and with ln=target links take
+// the colour of the file they resolve to under root
+func (p *Palette) entryColour(info os.FileInfo, path, root string) string {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return p.keyColour(p.fileKey(info.Mode(), info, path), info.Name())
+	}
+	resolved, err := ResolveLink(path, root)
+	var target os.FileInfo
+	if err == nil {
+		target, err = os.Lstat(resolved)
+	}
+	switch {
+	case err != nil && p.keys["or"] != "":
+		return p.keys["or"]
+	case err == nil && p.keys["ln"] == "target":
+		return p.keyColour(p.fileKey(target.Mode(), target, resolved), info.Name())
+	case p.keys["ln"] == "target":
+		return p.keys["no"]
+	}
+	return p.keyColour("ln", info.Name())
+}
+
+// start returns the escape sequence that switches to colour, or nothing for no colour
+func (p *Palette) start(colour string) string {
+	if colour == "" {
+		return ""
+	}
+	return p.keys["lc"] + colour + p.keys["rc"]
+}
+
+// end returns the escape sequence that ends a coloured name
+func (p *Palette) end() string {
+	if ec := p.keys["ec"]; ec != "" {
+		return ec
+	}
+	return p.keys["lc"] + p.keys["rs"] + p.keys["rc"]
+}
+
+// Columns are the long-format columns a palette can colour
+var Columns = []string{"inode", "blocks", "mode", "links", "user", "group", "size", "date", "git", "xattr"}
+
+// Set sets the colour for an LS_COLORS key such as "di"
+func (p *Palette) Set(key, colour string) error {
+	if _, known := colorKeys[key]; !known {
+		return fmt.Errorf("unknown colour key '%v'", key)
+	}
+	p.keys[key] = colour
+	return nil
+}
+
+// SetPattern colours names matching an LS_COLORS-style pattern such as
+// "*.tar", taking precedence over earlier patterns
+func (p *Palette) SetPattern(pattern, colour string) error {
+	if !strings.HasPrefix(pattern, "*") {
+		pattern = "*" + pattern
+	}
+	if strings.ContainsAny(pattern[1:], "*?[") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%v'", pattern)
+		}
+		p.patterns = append(p.patterns, colorPattern{glob: pattern, colour: colour})
+	} else {
+		p.patterns = append(p.patterns, colorPattern{suffix: pattern[1:], colour: colour})
+	}
+	return nil
+}
+
+// SetColumn sets the colour of one of the long-format Columns
+func (p *Palette) SetColumn(name, colour string) error {
+	for _, column := range Columns {
+		if column == name {
+			if p.columns == nil {
+				p.columns = map[string]string{}
+			}
+			p.columns[name] = colour
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown column '%v'", name)
+}
+
+// palette is the set of colours for this run
+var palette = DefaultPalette()
+
+// SetPalette makes p the colours names are painted in
+func SetPalette(p *Palette) {
+	palette = p
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestPalette_Capability(t *testing.T) {
+	dir := t.TempDir()
+	path := joinPath(dir, "ping")
+	os.WriteFile(path, nil, 0o755)
+	info, _ := os.Lstat(path)
+
+	p := DefaultPalette()
+	if got := p.entryColour(info, path, ""); got != "01;32" {
+		t.Errorf("executable without capabilities = %q, want ex", got)
+	}
+
+	// A version 2 capability set granting cap_net_raw
+	capability := []byte{0, 0, 0, 2, 0, 0x20, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if err := syscall.Setxattr(path, "security.capability", capability, 0); err != nil {
+		t.Skipf("cannot set file capabilities here: %v", err)
+	}
+	if !HasCapability(path) {
+		t.Fatalf("HasCapability(%v) = false after setting one", path)
+	}
+	if got := p.entryColour(info, path, ""); got != "30;41" {
+		t.Errorf("executable with capabilities = %q, want ca", got)
+	}
+}
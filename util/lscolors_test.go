@@ -0,0 +1,222 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLSColors(t *testing.T) {
+	p, err := ParseLSColors("di=01;33:ex=32::*.txt=35:*.TXT=36:*.tar.gz=31:*.txt=34:*README*=04:rs=00")
+	if err != nil {
+		t.Fatalf("ParseLSColors: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		mode os.FileMode
+		file string
+		want string
+	}{
+		{name: "directory", mode: os.ModeDir | 0o755, file: "src", want: "01;33"},
+		{name: "executable", mode: 0o755, file: "run.txt", want: "32"},
+		{name: "last suffix wins", mode: 0o644, file: "notes.txt", want: "34"},
+		{name: "suffixes are case-sensitive", mode: 0o644, file: "NOTES.TXT", want: "36"},
+		{name: "multi-dot suffix", mode: 0o644, file: "src.tar.gz", want: "31"},
+		{name: "glob", mode: 0o644, file: "README.md", want: "04"},
+		{name: "unset key keeps its default", mode: os.ModeSymlink | 0o777, file: "link", want: "01;36"},
+		{name: "built-in archives are replaced", mode: 0o644, file: "a.zip", want: "0"},
+	}
+	for _, tt := range tests {
+		if got := p.colour(tt.mode, tt.file); got != tt.want {
+			t.Errorf("%s: colour(%v, %q) = %q, want %q", tt.name, tt.mode, tt.file, got, tt.want)
+		}
+	}
+
+	if got := p.end(); got != "\033[00m" {
+		t.Errorf("end() = %q, want the rs sequence", got)
+	}
+}
+
+func TestParseLSColors_Errors(t *testing.T) {
+	for _, spec := range []string{"di", "zz=01", "di=01\\", "*[=01", "lc=\\x"} {
+		if _, err := ParseLSColors(spec); err == nil {
+			t.Errorf("ParseLSColors(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestParseLSColors_EndAndEscapes(t *testing.T) {
+	p, err := ParseLSColors(`lc=\e[:rc=m:ec=^[[0K\033[m:di=1`)
+	if err != nil {
+		t.Fatalf("ParseLSColors: %v", err)
+	}
+	if got := p.start(p.colour(os.ModeDir, "d")); got != "\033[1m" {
+		t.Errorf("start = %q", got)
+	}
+	if got := p.end(); got != "\033[0K\033[m" {
+		t.Errorf("end = %q, want ec", got)
+	}
+}
+
+func TestUnescapeColor(t *testing.T) {
+	tests := map[string]string{
+		"01;34":  "01;34",
+		`\e[`:    "\033[",
+		`\033[`:  "\033[",
+		`\x1b[`:  "\033[",
+		`^[[`:    "\033[",
+		`a\_b`:   "a b",
+		`\\`:     `\`,
+		`\^`:     "^",
+		`^?`:     "\x7f",
+		`\a\t\n`: "\a\t\n",
+		`\101\7`: "A\a",
+	}
+	for in, want := range tests {
+		got, err := unescapeColor(in)
+		if err != nil || got != want {
+			t.Errorf("unescapeColor(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestPalette_Links(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(joinPath(dir, "sub"), 0o755)
+	os.Symlink("sub", joinPath(dir, "good"))
+	os.Symlink("gone", joinPath(dir, "bad"))
+	good, _ := os.Lstat(joinPath(dir, "good"))
+	bad, _ := os.Lstat(joinPath(dir, "bad"))
+
+	p, _ := ParseLSColors("ln=target:or=01;31:mi=05")
+	if got := p.entryColour(good, joinPath(dir, "good"), ""); got != "01;34" {
+		t.Errorf("ln=target link to a directory = %q, want the directory colour", got)
+	}
+	if got := p.entryColour(bad, joinPath(dir, "bad"), ""); got != "01;31" {
+		t.Errorf("broken link = %q, want or", got)
+	}
+
+	p = DefaultPalette()
+	if got := p.entryColour(bad, joinPath(dir, "bad"), ""); got != "01;36" {
+		t.Errorf("broken link without or = %q, want ln", got)
+	}
+}
+
+func TestSetPalette(t *testing.T) {
+	old := palette
+	t.Cleanup(func() { palette = old })
+
+	p, _ := ParseLSColors("di=07:*.log=33:mi=05")
+	SetPalette(p)
+
+	dir := t.TempDir()
+	os.Mkdir(joinPath(dir, "d"), 0o755)
+	os.WriteFile(joinPath(dir, "x.log"), nil, 0o644)
+	os.Symlink("none", joinPath(dir, "z"))
+
+	names, err := ReadDirNames(dir, Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"\033[07md\033[0m", "\033[33mx.log\033[0m", "\033[01;36mz\033[0m"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("names[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	lines, _ := ReadDirNamesLong(dir, Flags{})
+	if got := lines[len(lines)-1]; !strings.HasSuffix(got, " -> \033[05mnone\033[0m") {
+		t.Errorf("broken link line = %q, want the target coloured with mi", got)
+	}
+}
+
+func TestPalette_PermissionClasses(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct {
+		name string
+		mode os.FileMode
+		dir  bool
+		want string
+	}{
+		{name: "setuid", mode: 0o755 | os.ModeSetuid, want: "37;41"},
+		{name: "setgid", mode: 0o755 | os.ModeSetgid, want: "30;43"},
+		{name: "setuid-noexec.tar", mode: 0o644 | os.ModeSetuid, want: "37;41"},
+		{name: "plain", mode: 0o755, want: "01;32"},
+		{name: "sticky", mode: 0o755 | os.ModeSticky, dir: true, want: "37;44"},
+		{name: "other-writable", mode: 0o777, dir: true, want: "34;42"},
+		{name: "tmp", mode: 0o777 | os.ModeSticky, dir: true, want: "30;42"},
+		{name: "private", mode: 0o700, dir: true, want: "01;34"},
+	}
+	for _, f := range files {
+		path := joinPath(dir, f.name)
+		if f.dir {
+			os.Mkdir(path, 0o700)
+		} else {
+			os.WriteFile(path, nil, 0o600)
+		}
+		if err := os.Chmod(path, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := DefaultPalette()
+	for _, f := range files {
+		path := joinPath(dir, f.name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.entryColour(info, path, ""); got != f.want {
+			t.Errorf("%v (%v): colour = %q, want %q", f.name, info.Mode(), got, f.want)
+		}
+	}
+}
+
+func TestPalette_HardLinks(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(joinPath(dir, "one"), nil, 0o644)
+	os.Link(joinPath(dir, "one"), joinPath(dir, "two"))
+	os.WriteFile(joinPath(dir, "single"), nil, 0o644)
+
+	p, _ := ParseLSColors("mh=44")
+	for name, want := range map[string]string{"one": "44", "two": "44", "single": "0"} {
+		path := joinPath(dir, name)
+		info, _ := os.Lstat(path)
+		if got := p.entryColour(info, path, ""); got != want {
+			t.Errorf("%v: colour = %q, want %q", name, got, want)
+		}
+	}
+
+	// mh has no colour by default
+	info, _ := os.Lstat(joinPath(dir, "one"))
+	if got := DefaultPalette().entryColour(info, joinPath(dir, "one"), ""); got != "0" {
+		t.Errorf("hard link with the default palette = %q, want the file colour", got)
+	}
+}
+
+func TestPalette_DisabledClasses(t *testing.T) {
+	// dircolors turns classes off with 00, which must leave a hard-linked
+	// file its extension colour
+	dir := t.TempDir()
+	os.WriteFile(joinPath(dir, "a.tar"), nil, 0o644)
+	os.Link(joinPath(dir, "a.tar"), joinPath(dir, "b.tar"))
+
+	for _, spec := range []string{"mh=00:*.tar=01;31", "mh=0:*.tar=01;31", "mh=:*.tar=01;31"} {
+		p, err := ParseLSColors(spec)
+		if err != nil {
+			t.Fatalf("ParseLSColors(%q) error: %v", spec, err)
+		}
+		path := joinPath(dir, "a.tar")
+		info, _ := os.Lstat(path)
+		if got := p.entryColour(info, path, ""); got != "01;31" {
+			t.Errorf("%q: colour = %q, want the extension colour", spec, got)
+		}
+	}
+
+	p, _ := ParseLSColors("su=00:ex=01;32")
+	if got := p.fileKey(os.ModeSetuid|0o755, nil, ""); got != "ex" {
+		t.Errorf("setuid executable with su=00 = %q, want ex", got)
+	}
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// credentials are the effective ids of the running process, which decide
+// which class of a file's permission bits applies to it
+type credentials struct {
+	uid, gid uint32
+	groups   []uint32 // supplementary groups
+}
+
+// cachedCredentials holds processCredentials' answer once it has one;
+// listings run on one goroutine, so it needs no lock
+var cachedCredentials *credentials
+
+// processCredentials returns the credentials of this process, read on
+// first use
+func processCredentials() credentials {
+	if cachedCredentials == nil {
+		creds := credentials{uid: uint32(os.Geteuid()), gid: uint32(os.Getegid())}
+		groups, _ := syscall.Getgroups()
+		for _, g := range groups {
+			creds.groups = append(creds.groups, uint32(g))
+		}
+		cachedCredentials = &creds
+	}
+	return *cachedCredentials
+}
+
+// inGroup reports whether gid is one of the supplementary groups
+func (c credentials) inGroup(gid uint32) bool {
+	for _, g := range c.groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// explainPerms says what the mode bits of a file owned by uid and gid let
+// creds do, and which class of them applies: root's, the owner's, the
+// group's through the primary or a supplementary group, or everyone else's.
+// groupName names the group, as the group column would
+func explainPerms(creds credentials, uid, gid uint32, mode os.FileMode, groupName string) string {
+	perm := mode.Perm()
+	switch {
+	case creds.uid == 0:
+		// Root may read and write anything, and run what anyone can
+		if mode.IsDir() || perm&0o111 != 0 {
+			return "rwx root"
+		}
+		return "rw- root"
+	case creds.uid == uid:
+		return rwx(perm>>6) + " owner"
+	case creds.gid == gid:
+		return rwx(perm>>3) + " group " + groupName
+	case creds.inGroup(gid):
+		return rwx(perm>>3) + " supplementary group " + groupName
+	}
+	return rwx(perm) + " other"
+}
+
+// rwx spells out the low three permission bits
+func rwx(bits os.FileMode) string {
+	s := []byte("---")
+	for i, c := range "rwx" {
+		if bits&(4>>i) != 0 {
+			s[i] = byte(c)
+		}
+	}
+	return string(s)
+}
+
+// ModeString renders mode as ls does: a type letter, '-' for a regular
+// file, then rwx for owner, group and others, with s or S in place of the
+// owner's and group's x for setuid and setgid, and t or T for sticky
+func ModeString(mode os.FileMode) string {
+	kind := TypeLetter(mode)
+	if kind == 'f' {
+		kind = '-'
+	}
+	perm := mode.Perm()
+	b := []byte{kind}
+	b = append(b, rwx(perm>>6)...)
+	b = append(b, rwx(perm>>3)...)
+	b = append(b, rwx(perm)...)
+	special(b, 3, mode&os.ModeSetuid != 0, 's')
+	special(b, 6, mode&os.ModeSetgid != 0, 's')
+	special(b, 9, mode&os.ModeSticky != 0, 't')
+	return string(b)
+}
+
+// special marks the x at b[i] as letter when set is true, or as its
+// capital when the execute bit beneath is not set
+func special(b []byte, i int, set bool, letter byte) {
+	switch {
+	case !set:
+	case b[i] == 'x':
+		b[i] = letter
+	default:
+		b[i] = letter - 'a' + 'A'
+	}
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExplainPerms(t *testing.T) {
+	user := credentials{uid: 1000, gid: 100, groups: []uint32{27, 100}}
+	tests := []struct {
+		name     string
+		creds    credentials
+		uid, gid uint32
+		mode     os.FileMode
+		want     string
+	}{
+		{name: "owner", creds: user, uid: 1000, gid: 5, mode: 0o754, want: "rwx owner"},
+		{name: "owner bits apply even when others have more", creds: user, uid: 1000, gid: 100, mode: 0o077, want: "--- owner"},
+		{name: "primary group", creds: user, uid: 0, gid: 100, mode: 0o750, want: "r-x group staff"},
+		{name: "supplementary group", creds: user, uid: 0, gid: 27, mode: 0o640, want: "r-- supplementary group staff"},
+		{name: "other", creds: user, uid: 0, gid: 0, mode: 0o644, want: "r-- other"},
+		{name: "root file", creds: credentials{}, uid: 1000, gid: 100, mode: 0o600, want: "rw- root"},
+		{name: "root executable", creds: credentials{}, uid: 1000, gid: 100, mode: 0o700, want: "rwx root"},
+		{name: "root directory", creds: credentials{}, uid: 1000, gid: 100, mode: os.ModeDir | 0o600, want: "rwx root"},
+	}
+	for _, tt := range tests {
+		if got := explainPerms(tt.creds, tt.uid, tt.gid, tt.mode, "staff"); got != tt.want {
+			t.Errorf("%v: explainPerms() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadDirNamesLong_OctalPerms(t *testing.T) {
+	base := t.TempDir()
+	path := joinPath(base, "run")
+	os.WriteFile(path, nil, 0o755)
+	os.Chmod(path, 0o755|os.ModeSetuid|os.ModeSticky)
+
+	lines, err := ReadDirNamesLong(base, Flags{OctalPerms: true, PermsExplain: true, NoColor: true})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	fields := []string{"5755", "-rwsr-xr-t"}
+	for i, want := range fields {
+		if got := strings.Fields(lines[1]); i >= len(got) || got[i] != want {
+			t.Errorf("field %d of %q, want %q", i, lines[1], want)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want string
+	}{
+		{mode: 0o644, want: "-rw-r--r--"},
+		{mode: os.ModeDir | 0o755, want: "drwxr-xr-x"},
+		{mode: os.ModeDir | os.ModeSticky | 0o777, want: "drwxrwxrwt"},
+		{mode: os.ModeDir | os.ModeSticky | 0o770, want: "drwxrwx--T"},
+		{mode: os.ModeSymlink | 0o777, want: "lrwxrwxrwx"},
+		{mode: os.ModeSetuid | 0o755, want: "-rwsr-xr-x"},
+		{mode: os.ModeSetuid | os.ModeSetgid | 0o644, want: "-rwSr-Sr--"},
+		{mode: os.ModeSetgid | 0o2755, want: "-rwxr-sr-x"},
+		{mode: os.ModeNamedPipe | 0o600, want: "prw-------"},
+		{mode: os.ModeSocket | 0o755, want: "srwxr-xr-x"},
+		{mode: os.ModeDevice | 0o660, want: "brw-rw----"},
+		{mode: os.ModeDevice | os.ModeCharDevice | 0o666, want: "crw-rw-rw-"},
+	}
+	for _, tt := range tests {
+		if got := ModeString(tt.mode); got != tt.want {
+			t.Errorf("ModeString(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// QuotingStyle is how names are quoted and escaped for display (--quoting-style)
+type QuotingStyle int
+
+const (
+	QuoteDefault           QuotingStyle = iota // shell-escape on a terminal, literal otherwise; Print resolves it
+	QuoteLiteral                               // names as they are (-N)
+	QuoteLocale                                // ‘name’, with C escapes
+	QuoteShell                                 // 'quoted' only when the shell needs it
+	QuoteShellAlways                           // always 'quoted'
+	QuoteShellEscape                           // like shell, with $'\n' for unprintable characters
+	QuoteShellEscapeAlways                     // like shell-always, with $'\n' for unprintable characters
+	QuoteC                                     // "name" with C escapes (-Q)
+	QuoteEscape                                // C escapes and "\ " without quotes (-b)
+)
+
+// quotingStyles are the --quoting-style names, as in GNU ls
+var quotingStyles = map[string]QuotingStyle{
+	"literal":             QuoteLiteral,
+	"locale":              QuoteLocale,
+	"clocale":             QuoteLocale,
+	"shell":               QuoteShell,
+	"shell-always":        QuoteShellAlways,
+	"shell-escape":        QuoteShellEscape,
+	"shell-escape-always": QuoteShellEscapeAlways,
+	"c":                   QuoteC,
+	"c-maybe":             QuoteC,
+	"escape":              QuoteEscape,
+}
+
+// ParseQuotingStyle parses a --quoting-style argument
+func ParseQuotingStyle(s string) (QuotingStyle, error) {
+	if style, ok := quotingStyles[s]; ok {
+		return style, nil
+	}
+	return QuoteDefault, fmt.Errorf("invalid argument '%v' for '--quoting-style' (valid: literal, locale, shell, shell-always, shell-escape, shell-escape-always, c, escape)", s)
+}
+
+// shellSafe are the characters a shell word needs no quoting for
+const shellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+// Quote returns name as it should be displayed in style. With hideControl
+// (-q), unprintable characters and bytes that are not UTF-8 that the style
+// leaves as they are become '?'
+func Quote(name string, style QuotingStyle, hideControl bool) string {
+	quoted := name
+	switch style {
+	case QuoteLocale:
+		left, right := localeQuotes()
+		quoted = left + cEscape(name, right, false) + right
+	case QuoteShell, QuoteShellAlways:
+		if style == QuoteShellAlways || needsShellQuotes(name) {
+			quoted = shellQuote(name)
+		}
+	case QuoteShellEscape, QuoteShellEscapeAlways:
+		if hasUnprintable(name) {
+			quoted = shellEscape(name)
+		} else if style == QuoteShellEscapeAlways || needsShellQuotes(name) {
+			quoted = shellQuote(name)
+		}
+	case QuoteC:
+		quoted = `"` + cEscape(name, `"`, false) + `"`
+	case QuoteEscape:
+		quoted = cEscape(name, "", true)
+	}
+	if hideControl {
+		quoted = hideUnprintable(quoted)
+	}
+	return quoted
+}
+
+// alignsQuotes reports whether style quotes only the names that need it,
+// so that unquoted names need a leading space to line up with the rest
+func alignsQuotes(style QuotingStyle) bool {
+	return style == QuoteShell || style == QuoteShellEscape
+}
+
+// isQuoted reports whether a name displayed in a shell style starts with a quote
+func isQuoted(display string) bool {
+	return strings.HasPrefix(display, "'") || strings.HasPrefix(display, `"`) || strings.HasPrefix(display, "$'")
+}
+
+// quoteNames returns how the names of entries are displayed. When some
+// are quoted in a shell style, the rest get a leading space to line up
+func quoteNames(entries []os.FileInfo, flag Flags) []string {
+	names := make([]string, len(entries))
+	aligned := false
+	for i, entry := range entries {
+		names[i] = Quote(entry.Name(), flag.Quoting, flag.HideControl)
+		aligned = aligned || alignsQuotes(flag.Quoting) && isQuoted(names[i])
+	}
+	if aligned {
+		for i, name := range names {
+			if !isQuoted(name) {
+				names[i] = " " + name
+			}
+		}
+	}
+	return names
+}
+
+// nextPrintable decodes the character at the start of s and reports
+// whether it can be shown as it is. Bytes that are not UTF-8 cannot, while
+// format characters such as the zero-width joiner in emoji sequences can,
+// except for the bidirectional overrides that can disguise a name
+func nextPrintable(s string) (size int, ok bool) {
+	r, size := DecodeRune(s)
+	if r == runeError && size <= 1 {
+		return size, false
+	}
+	return size, printable(r)
+}
+
+// printable rejects controls, spaces other than ' ', line and paragraph
+// separators, surrogates, private use characters and noncharacters.
+// Unassigned code points pass, as a newer Unicode may have given them glyphs
+func printable(r rune) bool {
+	switch {
+	case r == '\u200e' || r == '\u200f', r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return false
+	case isFormat(r):
+		return true
+	case isControl(r), r >= 0xd800 && r <= 0xdfff, r >= 0xe000 && r <= 0xf8ff, r >= 0xf0000:
+		return false
+	case r >= 0xfdd0 && r <= 0xfdef, r&0xfffe == 0xfffe:
+		return false
+	}
+	return r == ' ' || !inTable(r, separatorRanges)
+}
+
+// separatorRanges are the space, line and paragraph separators, categories
+// Zs, Zl and Zp
+var separatorRanges = [][2]rune{
+	{0x20, 0x20}, {0xa0, 0xa0}, {0x1680, 0x1680}, {0x2000, 0x200a},
+	{0x2028, 0x2029}, {0x202f, 0x202f}, {0x205f, 0x205f}, {0x3000, 0x3000},
+}
+
+func hasUnprintable(name string) bool {
+	for i := 0; i < len(name); {
+		size, ok := nextPrintable(name[i:])
+		if !ok {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// hideUnprintable replaces each unprintable character, and each byte that
+// is not part of a UTF-8 character, with '?'
+func hideUnprintable(name string) string {
+	if !hasUnprintable(name) {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		size, ok := nextPrintable(name[i:])
+		if ok {
+			b.WriteString(name[i : i+size])
+		} else {
+			b.WriteByte('?')
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// needsShellQuotes reports whether a shell would take name as anything
+// other than one literal word
+func needsShellQuotes(name string) bool {
+	if name == "" || name[0] == '~' || name[0] == '#' {
+		return true
+	}
+	for i := 0; i < len(name); {
+		size, ok := nextPrintable(name[i:])
+		if !ok || size == 1 && !strings.ContainsRune(shellSafe, rune(name[i])) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// shellQuote quotes name for a shell: in double quotes when that avoids
+// escaping a single quote, and in single quotes otherwise
+func shellQuote(name string) string {
+	if strings.Contains(name, "'") && !strings.ContainsAny(name, "\"$`\\!") {
+		return `"` + name + `"`
+	}
+	return "'" + strings.ReplaceAll(name, "'", `'\''`) + "'"
+}
+
+// shellEscape quotes name for a shell, with each run of unprintable
+// characters as a $'...' escape between the quoted runs of the rest
+func shellEscape(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		start := i
+		for i < len(name) {
+			size, ok := nextPrintable(name[i:])
+			if !ok {
+				break
+			}
+			i += size
+		}
+		if i > start {
+			b.WriteString("'" + strings.ReplaceAll(name[start:i], "'", `'\''`) + "'")
+		}
+
+		start = i
+		for i < len(name) {
+			size, ok := nextPrintable(name[i:])
+			if ok {
+				break
+			}
+			i += size
+		}
+		if i > start {
+			b.WriteString("$'" + cEscape(name[start:i], "'", false) + "'")
+		}
+	}
+	return b.String()
+}
+
+// cEscape writes name with C escapes for backslashes, unprintable
+// characters and the quote it is to be enclosed in; with escapeSpace
+// spaces become "\ " too
+func cEscape(name, quote string, escapeSpace bool) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		size, ok := nextPrintable(name[i:])
+		switch {
+		case name[i] == '\\':
+			b.WriteString(`\\`)
+		case quote != "" && strings.HasPrefix(name[i:], quote):
+			b.WriteString(`\` + quote)
+		case name[i] == ' ' && escapeSpace:
+			b.WriteString(`\ `)
+		case ok:
+			b.WriteString(name[i : i+size])
+		default:
+			for _, c := range []byte(name[i : i+size]) {
+				b.WriteString(cEscapeByte(c))
+			}
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// cEscapeByte returns the C escape for an unprintable byte
+func cEscapeByte(c byte) string {
+	switch c {
+	case '\a':
+		return `\a`
+	case '\b':
+		return `\b`
+	case '\f':
+		return `\f`
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\v':
+		return `\v`
+	}
+	return fmt.Sprintf(`\%03o`, c)
+}
+
+// localeQuotes returns the quotation marks of the locale style: curly
+// quotes in a UTF-8 locale and apostrophes otherwise
+func localeQuotes() (left, right string) {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			if strings.Contains(value, "utf-8") || strings.Contains(value, "utf8") {
+				return "\u2018", "\u2019"
+			}
+			break
+		}
+	}
+	return "'", "'"
+}
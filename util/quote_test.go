@@ -0,0 +1,174 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	names := []string{"plain.txt", "a b", "it's", "a\nb", "tab\there", "\033[31mred", "back\\slash", `say "hi"`, "~home", "日本語", "\xff\xfe", "$HOME"}
+	tests := []struct {
+		style QuotingStyle
+		want  []string
+	}{
+		{QuoteLiteral, []string{"plain.txt", "a b", "it's", "a\nb", "tab\there", "\033[31mred", "back\\slash", `say "hi"`, "~home", "日本語", "\xff\xfe", "$HOME"}},
+		{QuoteShell, []string{"plain.txt", "'a b'", `"it's"`, "'a\nb'", "'tab\there'", "'\033[31mred'", `'back\slash'`, `'say "hi"'`, "'~home'", "日本語", "'\xff\xfe'", "'$HOME'"}},
+		{QuoteShellAlways, []string{"'plain.txt'", "'a b'", `"it's"`, "'a\nb'", "'tab\there'", "'\033[31mred'", `'back\slash'`, `'say "hi"'`, "'~home'", "'日本語'", "'\xff\xfe'", "'$HOME'"}},
+		{QuoteShellEscape, []string{"plain.txt", "'a b'", `"it's"`, `'a'$'\n''b'`, `'tab'$'\t''here'`, `$'\033''[31mred'`, `'back\slash'`, `'say "hi"'`, "'~home'", "日本語", `$'\377\376'`, "'$HOME'"}},
+		{QuoteShellEscapeAlways, []string{"'plain.txt'", "'a b'", `"it's"`, `'a'$'\n''b'`, `'tab'$'\t''here'`, `$'\033''[31mred'`, `'back\slash'`, `'say "hi"'`, "'~home'", "'日本語'", `$'\377\376'`, "'$HOME'"}},
+		{QuoteC, []string{`"plain.txt"`, `"a b"`, `"it's"`, `"a\nb"`, `"tab\there"`, `"\033[31mred"`, `"back\\slash"`, `"say \"hi\""`, `"~home"`, `"日本語"`, `"\377\376"`, `"$HOME"`}},
+		{QuoteEscape, []string{"plain.txt", `a\ b`, "it's", `a\nb`, `tab\there`, `\033[31mred`, `back\\slash`, `say\ "hi"`, "~home", "日本語", `\377\376`, "$HOME"}},
+		{QuoteLocale, []string{"‘plain.txt’", "‘a b’", "‘it's’", `‘a\nb’`, `‘tab\there’`, `‘\033[31mred’`, `‘back\\slash’`, `‘say "hi"’`, "‘~home’", "‘日本語’", `‘\377\376’`, "‘$HOME’"}},
+	}
+	for _, tt := range tests {
+		for i, name := range names {
+			if got := Quote(name, tt.style, false); got != tt.want[i] {
+				t.Errorf("Quote(%q, %v) = %q, want %q", name, tt.style, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestQuote_HideControl(t *testing.T) {
+	tests := []struct {
+		name  string
+		style QuotingStyle
+		want  string
+	}{
+		{"a\nb", QuoteLiteral, "a?b"},
+		{"\033[31mred", QuoteLiteral, "?[31mred"},
+		{"a\nb", QuoteShell, "'a?b'"},
+		{"\xffx", QuoteLiteral, "?x"},
+		{"日本語", QuoteLiteral, "日本語"},
+		// The escaping styles leave nothing unprintable to hide
+		{"a\nb", QuoteShellEscape, `'a'$'\n''b'`},
+		{"a\nb", QuoteC, `"a\nb"`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.name, tt.style, true); got != tt.want {
+			t.Errorf("Quote(%q, %v, hide) = %q, want %q", tt.name, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestQuote_BidiAndJoiners(t *testing.T) {
+	// A right-to-left override could make "evil<RLO>txt.exe" read as
+	// "evilexe.txt", so it is escaped; a ZWJ in an emoji sequence is not
+	if got := Quote("evil\u202etxt.exe", QuoteShellEscape, false); got != `'evil'$'\342\200\256''txt.exe'` {
+		t.Errorf("bidi override quoted as %q", got)
+	}
+	family := "\U0001f468\u200d\U0001f469"
+	if got := Quote(family, QuoteShellEscape, false); got != family {
+		t.Errorf("ZWJ sequence quoted as %q", got)
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want bool
+	}{
+		{'a', true}, {' ', true}, {'é', true}, {'\u0301', true}, {'日', true}, {'\U0001f600', true},
+		{'\u200d', true}, {'\u00ad', true}, {'\U000e0041', true},
+		{'\t', false}, {'\x7f', false}, {'\u0085', false}, {'\u00a0', false}, {'\u3000', false},
+		{'\u2028', false}, {'\ue000', false}, {'\ufdd0', false}, {'\uffff', false}, {'\U0010fffd', false},
+		{'\u202e', false},
+	}
+	for _, tt := range tests {
+		if got := printable(tt.r); got != tt.want {
+			t.Errorf("printable(%U) = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestQuote_LocaleFallback(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	if got := Quote("a b", QuoteLocale, false); got != "'a b'" {
+		t.Errorf("Quote() in the C locale = %q, want 'a b'", got)
+	}
+}
+
+func TestParseQuotingStyle(t *testing.T) {
+	for name, want := range quotingStyles {
+		if got, err := ParseQuotingStyle(name); err != nil || got != want {
+			t.Errorf("ParseQuotingStyle(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseQuotingStyle("perl"); err == nil {
+		t.Errorf("ParseQuotingStyle(\"perl\") expected an error")
+	}
+}
+
+func TestReadDirNames_Quoting(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a b", "plain", "new\nline"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Symlink("a b", filepath.Join(dir, "link"))
+
+	names, err := ReadDirNames(dir, Flags{NoColor: true, Quoting: QuoteShellEscape})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unquoted names get a space to line up with the quoted ones
+	want := []string{"'a b'", " link", `'new'$'\n''line'`, " plain"}
+	if len(names) != len(want) {
+		t.Fatalf("ReadDirNames() = %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("name %d = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	lines, _ := ReadDirNamesLong(dir, Flags{NoColor: true, Quoting: QuoteC})
+	if want := `"link" -> "a b"`; !hasSuffixLine(lines, want) {
+		t.Errorf("long lines = %q, want one ending in %q", lines, want)
+	}
+
+	names, _ = ReadDirNames(dir, Flags{Quoting: QuoteShell})
+	if want := " " + symlinkColour + "link" + reset; names[1] != want {
+		t.Errorf("coloured aligned name = %q, want %q", names[1], want)
+	}
+}
+
+func hasSuffixLine(lines []string, suffix string) bool {
+	for _, line := range lines {
+		if strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReadDirNames_RawBytes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b\xff", "a\xfe", "b\xfe"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := ReadDirNames(dir, Flags{NoColor: true, Quoting: QuoteLiteral})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a\xfe", "b\xfe", "b\xff"}; strings.Join(names, "/") != strings.Join(want, "/") {
+		t.Errorf("literal names = %q, want the raw bytes %q in byte order", names, want)
+	}
+
+	names, _ = ReadDirNames(dir, Flags{NoColor: true, Quoting: QuoteShellEscape})
+	if want := `'b'$'\377'`; names[2] != want {
+		t.Errorf("shell-escaped name = %q, want %q", names[2], want)
+	}
+
+	entries, err := ListEntries(dir, Flags{})
+	if err != nil || len(entries) != 3 || entries[0].Path != filepath.Join(dir, "a\xfe") || entries[0].Depth != 1 {
+		t.Errorf("ListEntries() = %v, %v", entries, err)
+	}
+}
@@ -3,10 +3,10 @@ package util
 import (
 	"fmt"
 	"os"
-	"os/user"
 	"sort"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type Flags struct {
@@ -15,26 +15,86 @@ type Flags struct {
 	Reverse    bool
 	Recursive  bool
 	TimeSort   bool
-}
-
-type fileDisplayInfo struct {
-	os.FileInfo
-
-	mode    string
-	links   string
-	user    string
-	group   string
-	size    string
-	modTime string
-}
 
-type maxWidths struct {
-	links int
-	user  int
-	group int
-	size  int
+	// Filters are find-style predicates; an entry is listed only if it passes all of them
+	Filters []Predicate
+	// IDNames are the names given to --user and --group, which Print checks
+	// once the resolver is known
+	IDNames []IDName
+	// FileSize scales the size column and BlockSize the allocated-blocks
+	// figures such as the total line (-h, --si, --block-size, -k)
+	FileSize  Scale
+	BlockSize Scale
+
+	Inode      bool // -i: show inode numbers
+	AllocSize  bool // -s: show allocated blocks
+	NumericIDs bool // -n: numeric uid and gid instead of names
+	NoOwner    bool // -g: leave out the owner column
+	NoGroup    bool // -o, -G: leave out the group column
+
+	Dereference bool // -L: show the files symlinks point to instead of the links
+	NoColor     bool // leave names uncoloured; Print sets it from Color
+
+	Color      ColorMode  // --color
+	ColorScale ColorScale // --color-scale
+	TrueColor  bool       // gradients in 24-bit colour rather than 256; Print sets it from COLORTERM
+
+	// Quoting is how names are quoted (--quoting-style, -Q, -b, -N); Print
+	// turns QuoteDefault into shell-escape on a terminal and literal
+	// otherwise. HideControl shows unprintable characters the style leaves
+	// alone as '?' (-q); Print sets it on a terminal unless ShowControl
+	Quoting     QuotingStyle
+	HideControl bool
+	ShowControl bool
+
+	Zero bool // --zero: end lines with NUL rather than newline, one name per line
+	JSON bool // --json: one JSON object per entry
+
+	// Indicator is which type indicators follow names (-F, -p,
+	// --indicator-style, --file-type); ClassifyAuto makes Print use
+	// classify on a terminal (--classify=auto)
+	Indicator    IndicatorStyle
+	ClassifyAuto bool
+
+	// Format is the layout outside long format (-1, -C, -x, -m, --format);
+	// TabSize, when set, pads grid columns with tabs that far apart (-T)
+	Format  Format
+	TabSize int
+
+	GridLong bool // --grid-long: compact long-format records, laid out in columns
+
+	// Columns, when set, are the fields of long listings, in order
+	// (--columns); Header puts a line of headings above them (--header)
+	Columns []string
+	Header  bool
+
+	OctalPerms   bool // --octal-perms: a column of permission bits in octal
+	PermsExplain bool // --perms-explain: a column of what the permissions let this user do, and why
+	Access       bool // --access: a column of what this user can actually do, as the kernel decides
+	Xattrs       bool // --xattrs: each entry's extended attributes on lines under it
+
+	Icons     IconMode // --icons
+	ShowIcons bool     // put icons before names; Print sets it from Icons
+
+	// Width is the output width given with -w; WidthSet tells -w 0, which
+	// means no limit, apart from no -w at all
+	Width    int
+	WidthSet bool
+
+	// Root, when set, is the directory absolute symlink targets are resolved
+	// under, for display and for -L (--root)
+	Root string
+
+	// Resolver, when set, replaces the system user database for owner and
+	// group names (--id-resolver, --id-root)
+	Resolver Resolver
+
+	// Depth is how far the directory being listed is below its command-line
+	// operand; Print sets it while walking a recursive listing
+	Depth int
 }
 
+// The escape sequences the default palette produces
 const (
 	reset = "\033[0m"
 
@@ -47,81 +107,208 @@ const (
 	archiveColour = "\033[01;31m"    // bold red
 )
 
-// ReadDirNames returns a list of file and directory names in dirPath
+// ReadDirNames returns a list of file and directory names in dirPath. With
+// -i or -s each name is prefixed by its inode or allocated size, and -s adds
+// a leading total line
 func ReadDirNames(dirPath string, flag Flags) ([]string, error) {
-	dir, err := os.Open(dirPath)
-	if err != nil {
-		return nil, err
-	}
-	defer dir.Close()
-
-	entries, err := dir.Readdir(-1)
+	entries, err := readEntries(dirPath, flag)
 	if err != nil {
 		return nil, err
 	}
+	sortEntries(entries, flag)
 
 	var names []string
-
-	// Add . and .. entries when showAll is true
-	if flag.ShowAll {
-		names = append(names, fmt.Sprintf("%s.%s", dirColour, reset))
-		names = append(names, fmt.Sprintf("%s..%s", dirColour, reset))
+	prefixes := make([]string, len(entries))
+	if flag.Inode || flag.AllocSize {
+		var columns []string
+		if flag.Inode {
+			columns = append(columns, "inode")
+		}
+		if flag.AllocSize {
+			columns = append(columns, "blocks")
+		}
+		records, totalBlocks := newRecords(dirPath, entries, flag.Dereference)
+		if flag.AllocSize {
+			names = append(names, "total "+flag.BlockSize.Format(totalBlocks*512, 1024))
+		}
+		for i, line := range formatRecords(records, columns, flag, false) {
+			prefixes[i] = line + " "
+		}
 	}
 
-	for _, entry := range entries {
+	quoted := quoteNames(entries, flag)
+	for i, entry := range entries {
 		name := entry.Name()
+		colour := nameColour(entry, joinPath(dirPath, name), flag)
+		icon := iconPrefix(flag, colour, entry, joinPath(dirPath, name))
+		names = append(names, prefixes[i]+icon+paintName(flag, colour, quoted[i])+TypeIndicator(entry.Mode(), flag.Indicator))
+	}
 
-		// Skip hidden files unless showAll is true
-		if !flag.ShowAll && strings.HasPrefix(name, ".") {
-			continue
+	return names, nil
+}
+
+// newRecords stats entries of dirPath for their columns, and adds up the
+// 512-byte blocks they take. With dereference, as for -L, entries that are
+// no longer links are read through the links they were listed as
+func newRecords(dirPath string, entries []os.FileInfo, dereference bool) ([]*longRecord, int64) {
+	records := make([]*longRecord, len(entries))
+	var totalBlocks int64
+	for i, info := range entries {
+		path := joinPath(dirPath, info.Name())
+		records[i] = &longRecord{
+			FileInfo: info,
+			path:     path,
+			stat:     Entry{FileInfo: info, Path: path}.Stat(),
+			follow:   dereference && info.Mode()&os.ModeSymlink == 0,
 		}
+		totalBlocks += int64(records[i].stat.Blocks)
+	}
+	return records, totalBlocks
+}
 
-		mode := entry.Mode()
-		colour := reset
+// ListEntries returns the entries of dirPath that would be listed, in
+// listing order, for output that formats them itself
+func ListEntries(dirPath string, flag Flags) ([]Entry, error) {
+	infos, err := readEntries(dirPath, flag)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(infos, flag)
 
-		switch {
-		case mode.IsDir():
-			colour = dirColour
+	entries := make([]Entry, len(infos))
+	for i, info := range infos {
+		entries[i] = Entry{FileInfo: info, Path: joinPath(dirPath, info.Name()), Depth: flag.Depth + 1}
+	}
+	return entries, nil
+}
 
-		case mode&os.ModeSymlink != 0:
-			colour = symlinkColour
+// ReadDirNamesLong returns the long-format lines for dirPath, starting
+// with the total line. The columns are those of --columns or, failing
+// that, the usual ones: -i and -s add leading inode and block columns,
+// -g, -o and -G drop the owner or group, and --grid-long leaves just the
+// mode, size, date and name. -n shows numeric ids and --header puts a
+// line of headings above the entries. Modes are marked '+' for an ACL and
+// '@' for other extended attributes, which --xattrs lists under each entry
+func ReadDirNamesLong(dirPath string, flag Flags) ([]string, error) {
+	entries, err := readEntries(dirPath, flag)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(entries, flag)
+
+	records, totalBlocks := newRecords(dirPath, entries, flag.Dereference)
+	quoted := quoteNames(entries, flag)
+	for i, r := range records {
+		colour := nameColour(r.FileInfo, r.path, flag)
+		r.name = iconPrefix(flag, colour, r.FileInfo, r.path) + paintName(flag, colour, quoted[i])
+		target := ""
+		if r.Mode()&os.ModeSymlink != 0 {
+			target = linkTarget(r.path, flag)
+		}
+		if target != "" {
+			// The arrow shows it is a link; the target gets the indicator
+			r.name += target
+		} else {
+			r.name += TypeIndicator(r.Mode(), flag.Indicator)
+		}
+	}
 
-		case mode&os.ModeSocket != 0:
-			colour = socketColour
+	columns := longColumnNames(flag)
+	if hasColumn(columns, "git") {
+		statuses := gitStatuses(dirPath)
+		for _, r := range records {
+			r.git = statuses[r.Name()]
+		}
+	}
 
-		case mode&os.ModeNamedPipe != 0:
-			colour = pipeColour
+	// st_blocks counts 512-byte units; totals default to 1024-byte blocks
+	lines := []string{"total " + flag.BlockSize.Format(totalBlocks*512, 1024)}
+	header := flag.Header && !flag.GridLong
+	formatted := formatRecords(records, columns, flag, header)
+	if !flag.Xattrs || flag.GridLong {
+		return append(lines, formatted...), nil
+	}
 
-		case mode&os.ModeDevice != 0:
-			colour = deviceColour
+	// --xattrs puts each entry's attributes on lines of their own under it
+	if header {
+		lines, formatted = append(lines, formatted[0]), formatted[1:]
+	}
+	for i, line := range formatted {
+		lines = append(lines, line)
+		lines = append(lines, xattrLines(records[i].path, records[i].xattrNames(), records[i].follow)...)
+	}
+	return lines, nil
+}
 
-		case mode&0o111 != 0:
-			colour = exeColour
+// nameColour returns the escape sequence for the name of the file at path:
+// its palette colour, or with --color-scale=name its age for plain files
+func nameColour(info os.FileInfo, path string, flag Flags) string {
+	if flag.ColorScale.Name && palette.fileKey(info.Mode(), info, path) == "fi" {
+		return palette.start(ageColour(time.Since(info.ModTime()), flag.TrueColor))
+	}
+	return palette.start(palette.entryColour(info, path, flag.Root))
+}
 
-		case strings.HasSuffix(name, ".tar") ||
-			strings.HasSuffix(name, ".gz") ||
-			strings.HasSuffix(name, ".tgz") ||
-			strings.HasSuffix(name, ".zip") ||
-			strings.HasSuffix(name, ".bz2") ||
-			strings.HasSuffix(name, ".xz"):
-			colour = archiveColour
+// linkTarget returns the " -> target" suffix for the symlink at path, with
+// the target coloured by the file it resolves to under flag.Root, or as
+// missing (mi) when there is none, and followed by its type indicator
+func linkTarget(path string, flag Flags) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	colour, suffix := palette.keys["mi"], ""
+	if resolved, err := ResolveLink(path, flag.Root); err == nil {
+		if info, err := os.Lstat(resolved); err == nil {
+			colour = palette.keyColour(palette.fileKey(info.Mode(), info, resolved), target)
+			suffix = TypeIndicator(info.Mode(), flag.Indicator)
 		}
+	}
+	return " -> " + paint(flag, palette.start(colour), Quote(target, flag.Quoting, flag.HideControl)) + suffix
+}
 
-		if flag.TimeSort {
-			names = InsertSortedByTime(name, colour, reset, dirPath, names)
-		} else {
-			names = InsertSorted(name, colour, reset, names)
-		}
+// paintName paints a name from quoteNames, leaving out of the colour the
+// space that lines an unquoted name up with quoted ones. Under the shell
+// styles a name that itself starts with a space is always quoted
+func paintName(flag Flags, colour, name string) string {
+	if alignsQuotes(flag.Quoting) && strings.HasPrefix(name, " ") {
+		return " " + paint(flag, colour, name[1:])
+	}
+	return paint(flag, colour, name)
+}
+
+// paint wraps text in colour, an escape sequence from the palette, or
+// leaves it bare when colour is off or empty
+func paint(flag Flags, colour, text string) string {
+	if flag.NoColor || colour == "" {
+		return text
 	}
+	return colour + text + palette.end()
+}
 
+// sortEntries orders entries for display: . and .. first, then by name or,
+// with -t, newest first with ties broken by name. -r reverses the lot
+func sortEntries(entries []os.FileInfo, flag Flags) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if flag.TimeSort && !isDotEntry(a.Name()) && !isDotEntry(b.Name()) && !a.ModTime().Equal(b.ModTime()) {
+			return a.ModTime().After(b.ModTime())
+		}
+		return LessName(a.Name(), b.Name())
+	})
 	if flag.Reverse {
-		Reverse(names)
+		Reverse(entries)
 	}
+}
 
-	return names, nil
+func isDotEntry(name string) bool {
+	return name == "." || name == ".."
 }
 
-func ReadDirNamesLong(dirPath string, flag Flags) ([]string, error) {
+// readEntries returns the entries of dirPath to be listed, with . and .. first
+// when showAll is set, hidden files skipped otherwise, and anything failing
+// one of the filters dropped
+func readEntries(dirPath string, flag Flags) ([]os.FileInfo, error) {
 	dir, err := os.Open(dirPath)
 	if err != nil {
 		return nil, err
@@ -133,17 +320,13 @@ func ReadDirNamesLong(dirPath string, flag Flags) ([]string, error) {
 		return nil, err
 	}
 
-	var displayInfos []fileDisplayInfo
-	var widths maxWidths
-	var totalBlocks int64
-
-	// Combine initial filtering and data gathering into a single list
-	filesToProcess := []os.FileInfo{}
+	var infos []os.FileInfo
 	if flag.ShowAll {
 		for _, special := range []string{".", ".."} {
-			info, err := os.Lstat(joinPath(dirPath, special))
-			if err == nil {
-				filesToProcess = append(filesToProcess, info)
+			path := joinPath(dirPath, special)
+			info, err := os.Lstat(path)
+			if err == nil && KeepFile(Entry{FileInfo: info, Path: path, Depth: flag.Depth + 1}, flag) {
+				infos = append(infos, info)
 			}
 		}
 	}
@@ -151,137 +334,24 @@ func ReadDirNamesLong(dirPath string, flag Flags) ([]string, error) {
 		if !flag.ShowAll && strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
-		filesToProcess = append(filesToProcess, entry)
-	}
-
-	for _, info := range filesToProcess {
-		fullPath := joinPath(dirPath, info.Name())
-		stat := getStat(fullPath)
-		totalBlocks += int64(stat.Blocks)
-
-		// Owner and group
-		uid := fmt.Sprint(stat.Uid)
-		owner := uid
-		if u, err := user.LookupId(uid); err == nil {
-			owner = u.Username
-		}
-		gid := fmt.Sprint(stat.Gid)
-		group := gid
-		if g, err := user.LookupGroupId(gid); err == nil {
-			group = g.Name
-		}
-
-		// Convert numeric fields to strings for length calculation
-		linksStr := fmt.Sprint(stat.Nlink)
-		sizeStr := fmt.Sprint(info.Size())
-
-		// Update max widths
-		if len(linksStr) > widths.links {
-			widths.links = len(linksStr)
-		}
-		if len(owner) > widths.user {
-			widths.user = len(owner)
-		}
-		if len(group) > widths.group {
-			widths.group = len(group)
-		}
-		if len(sizeStr) > widths.size {
-			widths.size = len(sizeStr)
-		}
-
-		// Store the processed info
-		displayInfos = append(displayInfos, fileDisplayInfo{
-			FileInfo: info,
-			mode:     info.Mode().String(),
-			links:    linksStr,
-			user:     owner,
-			group:    group,
-			size:     sizeStr,
-			modTime:  info.ModTime().Format("Jan _2 15:04"),
-		})
-	}
-
-	// Sort displayInfos by time if TimeSort is enabled
-	if flag.TimeSort {
-		// Sort by modification time (newest first)
-		for i := 0; i < len(displayInfos)-1; i++ {
-			for j := i + 1; j < len(displayInfos); j++ {
-				// Skip . and .. entries - they should stay at the beginning
-				if displayInfos[i].Name() == "." || displayInfos[i].Name() == ".." {
-					continue
-				}
-				if displayInfos[j].Name() == "." || displayInfos[j].Name() == ".." {
-					continue
-				}
-
-				// Compare modification times (newer first)
-				if displayInfos[j].ModTime().After(displayInfos[i].ModTime()) {
-					displayInfos[i], displayInfos[j] = displayInfos[j], displayInfos[i]
-				}
+		path := joinPath(dirPath, entry.Name())
+		if flag.Dereference && entry.Mode()&os.ModeSymlink != 0 {
+			// Broken links are listed as links
+			if target, err := StatLink(path, flag.Root, entry); err == nil {
+				entry = target
 			}
 		}
-	}
-
-	if flag.Reverse {
-		for i, j := 0, len(displayInfos)-1; i < j; i, j = i+1, j-1 {
-			displayInfos[i], displayInfos[j] = displayInfos[j], displayInfos[i]
-		}
-	}
-
-	var lines []string
-
-	for _, di := range displayInfos {
-		color := getFileColor(di.Mode(), di.Name())
-		fileName := fmt.Sprintf("%s%s%s", color, di.Name(), reset)
-
-		// Use the calculated max widths to format the line perfectly
-		line := fmt.Sprintf("%-10s %*s %-*s %-*s %*s %s %s",
-			di.mode,
-			widths.links, di.links,
-			widths.user, di.user,
-			widths.group, di.group,
-			widths.size, di.size,
-			di.modTime,
-			fileName,
-		)
-		if flag.TimeSort {
-			// When using time sort, we've already sorted the displayInfos array
-			// so we just append in order
-			lines = append(lines, line)
-		} else {
-			lines = InsertSortedLong(line, lines)
+		if !KeepFile(Entry{FileInfo: entry, Path: path, Depth: flag.Depth + 1}, flag) {
+			continue
 		}
+		infos = append(infos, entry)
 	}
-	lines = append([]string{fmt.Sprintf("total %d", totalBlocks/2)}, lines...)
-
-	return lines, nil
+	return infos, nil
 }
 
-// getFileColor remains the same as it's a perfect helper function
+// getFileColor returns the escape sequence for a file of the given mode and name
 func getFileColor(mode os.FileMode, name string) string {
-	switch {
-	case mode.IsDir():
-		return dirColour
-	case mode&os.ModeSymlink != 0:
-		return symlinkColour
-	case mode&os.ModeSocket != 0:
-		return socketColour
-	case mode&os.ModeNamedPipe != 0:
-		return pipeColour
-	case mode&os.ModeDevice != 0:
-		return deviceColour
-	case mode&0o111 != 0:
-		return exeColour
-	case strings.HasSuffix(name, ".tar"),
-		strings.HasSuffix(name, ".gz"),
-		strings.HasSuffix(name, ".tgz"),
-		strings.HasSuffix(name, ".zip"),
-		strings.HasSuffix(name, ".bz2"),
-		strings.HasSuffix(name, ".xz"):
-		return archiveColour
-	default:
-		return reset
-	}
+	return palette.start(palette.colour(mode, name))
 }
 
 // getStat also remains the same
@@ -321,7 +391,7 @@ func CollectDirectoriesRecursively(rootPaths []string, flags Flags) ([]string, e
 	visited := make(map[string]bool)
 
 	for _, rootPath := range rootPaths {
-		info, err := IsValidDir(rootPath)
+		info, err := IsValidDir(HostPath(rootPath, flags.Root))
 		if err != nil {
 			return nil, err
 		}
@@ -347,18 +417,25 @@ func CollectDirectoriesRecursively(rootPaths []string, flags Flags) ([]string, e
 
 // collectSubdirectories is a helper function that recursively collects subdirectories
 func collectSubdirectories(dirPath string, flags Flags, allDirs *[]string, visited map[string]bool) error {
-	// Prevent infinite loops with symlinks
-	absPath, err := getAbsPath(dirPath)
+	// Prevent infinite loops with symlinks; directories are known by device
+	// and inode so that the same one reached through two links counts once
+	readPath := HostPath(dirPath, flags.Root)
+	key, err := getAbsPath(readPath)
 	if err != nil {
 		return err
 	}
+	if info, err := os.Stat(readPath); err == nil {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			key = fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+		}
+	}
 
-	if visited[absPath] {
+	if visited[key] {
 		return nil
 	}
-	visited[absPath] = true
+	visited[key] = true
 
-	dir, err := os.Open(dirPath)
+	dir, err := os.Open(readPath)
 	if err != nil {
 		return err
 	}
@@ -369,7 +446,8 @@ func collectSubdirectories(dirPath string, flags Flags, allDirs *[]string, visit
 		return err
 	}
 
-	// Collect directories first
+	// Collect directories first. Links followed under --root keep the path
+	// they were reached by; HostPath finds them again for reading
 	var subdirs []string
 	for _, entry := range entries {
 		name := entry.Name()
@@ -386,6 +464,11 @@ func collectSubdirectories(dirPath string, flags Flags, allDirs *[]string, visit
 
 		if entry.IsDir() {
 			subdirs = append(subdirs, name)
+		} else if flags.Dereference && entry.Mode()&os.ModeSymlink != 0 {
+			target, err := StatLink(joinPath(readPath, name), flags.Root, entry)
+			if err == nil && target.IsDir() {
+				subdirs = append(subdirs, name)
+			}
 		}
 	}
 
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestReadDirNamesLong_Columns(t *testing.T) {
+	base := t.TempDir()
+	path := joinPath(base, "file.txt")
+	os.WriteFile(path, make([]byte, 5000), 0o644)
+
+	var stat syscall.Stat_t
+	if err := syscall.Lstat(path, &stat); err != nil {
+		t.Fatalf("Lstat() error: %v", err)
+	}
+	inode := fmt.Sprint(stat.Ino)
+	uid, gid := fmt.Sprint(stat.Uid), fmt.Sprint(stat.Gid)
+
+	tests := []struct {
+		name   string
+		flags  Flags
+		fields []string // expected leading fields, "" for any value
+		count  int      // expected number of whitespace-separated fields
+	}{
+		{name: "default", flags: Flags{}, fields: []string{"-rw-r--r--", "1"}, count: 9},
+		{name: "inode", flags: Flags{Inode: true}, fields: []string{inode, "-rw-r--r--"}, count: 10},
+		{name: "blocks", flags: Flags{AllocSize: true}, fields: []string{"", "-rw-r--r--"}, count: 10},
+		{name: "numeric ids", flags: Flags{NumericIDs: true}, fields: []string{"-rw-r--r--", "1", uid, gid, "5000"}, count: 9},
+		{name: "no owner", flags: Flags{NoOwner: true, NumericIDs: true}, fields: []string{"-rw-r--r--", "1", gid, "5000"}, count: 8},
+		{name: "no group", flags: Flags{NoGroup: true, NumericIDs: true}, fields: []string{"-rw-r--r--", "1", uid, "5000"}, count: 8},
+		{name: "neither", flags: Flags{NoOwner: true, NoGroup: true}, fields: []string{"-rw-r--r--", "1", "5000"}, count: 7},
+		{name: "grid long", flags: Flags{GridLong: true}, fields: []string{"-rw-r--r--", "5000"}, count: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ReadDirNamesLong(base, tt.flags)
+			if err != nil {
+				t.Fatalf("ReadDirNamesLong() error: %v", err)
+			}
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 lines, got %v", lines)
+			}
+			fields := strings.Fields(StripANSI(lines[1]))
+			if len(fields) != tt.count {
+				t.Errorf("expected %d fields, got %d: %q", tt.count, len(fields), lines[1])
+			}
+			for i, want := range tt.fields {
+				if want != "" && (i >= len(fields) || fields[i] != want) {
+					t.Errorf("field %d: expected %q in %q", i, want, lines[1])
+				}
+			}
+		})
+	}
+}
+
+func TestReadDirNames_Prefixes(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "a.txt"), make([]byte, 5000), 0o644)
+	os.WriteFile(joinPath(base, "b.txt"), nil, 0o644)
+
+	names, err := ReadDirNames(base, Flags{Inode: true, AllocSize: true})
+	if err != nil {
+		t.Fatalf("ReadDirNames() error: %v", err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("expected total line and 2 names, got %v", names)
+	}
+	if !strings.HasPrefix(names[0], "total ") {
+		t.Errorf("expected a total line first, got %q", names[0])
+	}
+
+	for i, name := range []string{"a.txt", "b.txt"} {
+		fields := strings.Fields(StripANSI(names[i+1]))
+		if len(fields) != 3 || fields[2] != name {
+			t.Errorf("expected inode, blocks and %s, got %q", name, names[i+1])
+		}
+	}
+
+	// The prefix columns are padded to a common width
+	if len(StripANSI(names[1])) != len(StripANSI(names[2])) {
+		t.Errorf("expected aligned prefixes, got %q and %q", names[1], names[2])
+	}
+}
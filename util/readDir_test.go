@@ -261,3 +261,31 @@ func TestGetFileColor(t *testing.T) {
 		})
 	}
 }
+
+func TestReadDirNames_NoColor(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(joinPath(dir, "sub"), 0o755)
+	os.WriteFile(joinPath(dir, "run.sh"), nil, 0o755)
+	os.Symlink("run.sh", joinPath(dir, "link"))
+
+	names, err := ReadDirNames(dir, Flags{NoColor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"link", "run.sh", "sub"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDirNames(NoColor) = %q, want %q", names, want)
+	}
+
+	lines, err := ReadDirNamesLong(dir, Flags{NoColor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "\033") {
+			t.Errorf("long line %q contains an escape sequence", line)
+		}
+	}
+	if !strings.HasSuffix(lines[1], "link -> run.sh") {
+		t.Errorf("link line = %q, want it to end in link -> run.sh", lines[1])
+	}
+}
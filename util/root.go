@@ -0,0 +1,172 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// maxSymlinks bounds how many links ResolveLink follows before giving up, like the kernel's ELOOP
+const maxSymlinks = 40
+
+// ResolveLink follows the symlink at path, and any links met on the way,
+// to the path of the file it finally names. Absolute targets, and ..
+// components that would climb above it, are taken relative to root, as if
+// the listing ran chrooted there; an empty root means the host's /.
+// For a broken link the path reached so far comes back with the error
+func ResolveLink(path, root string) (string, error) {
+	floor := "/"
+	if root != "" {
+		floor = absPath(root)
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return path, err
+	}
+
+	current := parentDir(absPath(path))
+	if !within(current, floor) {
+		// A link outside the root resolves relative links against the host
+		floor = "/"
+	}
+
+	pending := splitPath(target)
+	if strings.HasPrefix(target, "/") {
+		current = floor
+	}
+
+	for followed := 1; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+
+		switch part {
+		case ".":
+			continue
+		case "..":
+			if current != floor {
+				current = parentDir(current)
+			}
+			continue
+		}
+
+		next := joinPath(current, part)
+		info, err := os.Lstat(next)
+		if err != nil {
+			if len(pending) > 0 {
+				next = joinPath(next, strings.Join(pending, "/"))
+			}
+			return next, err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		if followed++; followed > maxSymlinks {
+			return next, syscall.ELOOP
+		}
+		link, err := os.Readlink(next)
+		if err != nil {
+			return next, err
+		}
+		if strings.HasPrefix(link, "/") {
+			current = floor
+		}
+		pending = append(splitPath(link), pending...)
+	}
+
+	return current, nil
+}
+
+// HostPath returns where path is on the host when the directories along it
+// may be symlinks to resolve under root, as in the paths -R follows links
+// through with -L. Without a root the host resolves them itself
+func HostPath(path, root string) string {
+	if root == "" {
+		return path
+	}
+	current := ""
+	if strings.HasPrefix(path, "/") {
+		current = "/"
+	}
+	for _, part := range splitPath(path) {
+		current = joinPath(current, part)
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if resolved, err := ResolveLink(current, root); err == nil {
+				current = resolved
+			}
+		}
+	}
+	return current
+}
+
+// StatLink returns the info of the file the symlink at path names, resolved
+// under root, keeping the link's own name
+func StatLink(path, root string, link os.FileInfo) (os.FileInfo, error) {
+	target, err := ResolveLink(path, root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: link.Name()}, nil
+}
+
+// renamedInfo is a FileInfo shown under another name, such as a
+// dereferenced symlink listed under the link's name
+type renamedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (r renamedInfo) Name() string {
+	return r.name
+}
+
+// absPath makes p absolute against the working directory and removes
+// duplicate slashes, trailing slashes and . components
+func absPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		if wd, err := os.Getwd(); err == nil {
+			p = wd + "/" + p
+		}
+	}
+	var parts []string
+	for _, part := range splitPath(p) {
+		if part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// splitPath returns the non-empty components of p
+func splitPath(p string) []string {
+	var parts []string
+	for _, part := range strings.Split(p, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// parentDir returns the directory containing the absolute path p
+func parentDir(p string) string {
+	i := strings.LastIndexByte(p, '/')
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// within reports whether p is dir or below it
+func within(p, dir string) bool {
+	if dir == "/" {
+		return strings.HasPrefix(p, "/")
+	}
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
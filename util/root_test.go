@@ -0,0 +1,212 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// makeRoot builds a small container-style tree:
+//
+//	root/bin/sh            regular file
+//	root/etc/              directory
+//	root/usr/bin/python    -> /usr/bin/python3 (absolute, inside the root)
+//	root/usr/bin/python3   regular file
+//	root/usr/lib -> ../../../../../lib (climbs above the root)
+//	root/lib/              directory
+//	root/loop/a -> b, b -> a
+//	root/dangling -> /missing
+//	root/etcdir -> /etc, root/shell -> bin/sh
+func makeRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"bin", "etc", "usr/bin", "lib", "loop"} {
+		if err := os.MkdirAll(joinPath(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"bin/sh", "usr/bin/python3"} {
+		if err := os.WriteFile(joinPath(root, file), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"usr/bin/python": "/usr/bin/python3",
+		"usr/lib":        "../../../../../lib",
+		"loop/a":         "b",
+		"loop/b":         "a",
+		"dangling":       "/missing",
+		"etcdir":         "/etc",
+		"shell":          "bin/sh",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, joinPath(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestResolveLink(t *testing.T) {
+	root := makeRoot(t)
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "usr/bin/python", want: "usr/bin/python3"},
+		{link: "usr/lib", want: "lib"},
+		{link: "etcdir", want: "etc"},
+		{link: "shell", want: "bin/sh"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveLink(joinPath(root, tt.link), root)
+		if err != nil {
+			t.Errorf("ResolveLink(%v) error: %v", tt.link, err)
+			continue
+		}
+		if want := absPath(joinPath(root, tt.want)); got != want {
+			t.Errorf("ResolveLink(%v) = %v, want %v", tt.link, got, want)
+		}
+	}
+}
+
+func TestResolveLink_Errors(t *testing.T) {
+	root := makeRoot(t)
+
+	if _, err := ResolveLink(joinPath(root, "loop/a"), root); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("loop: error = %v, want ELOOP", err)
+	}
+
+	got, err := ResolveLink(joinPath(root, "dangling"), root)
+	if !os.IsNotExist(err) {
+		t.Errorf("dangling: error = %v, want not exist", err)
+	}
+	if !strings.HasSuffix(got, "/missing") || !within(got, absPath(root)) {
+		t.Errorf("dangling: path = %v, want /missing under the root", got)
+	}
+
+	if _, err := ResolveLink(joinPath(root, "bin/sh"), root); err == nil {
+		t.Errorf("expected an error resolving a regular file")
+	}
+}
+
+func TestResolveLink_HostRoot(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(joinPath(dir, "file"), nil, 0o644)
+	os.Symlink(joinPath(dir, "file"), joinPath(dir, "abs"))
+
+	got, err := ResolveLink(joinPath(dir, "abs"), "")
+	if err != nil || got != absPath(joinPath(dir, "file")) {
+		t.Errorf("ResolveLink without a root = %v, %v", got, err)
+	}
+}
+
+func TestStatLink(t *testing.T) {
+	root := makeRoot(t)
+	link := joinPath(root, "etcdir")
+	linfo, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := StatLink(link, root, linfo)
+	if err != nil {
+		t.Fatalf("StatLink: %v", err)
+	}
+	if info.Name() != "etcdir" || !info.IsDir() {
+		t.Errorf("StatLink = %v (dir %v), want etcdir as a directory", info.Name(), info.IsDir())
+	}
+}
+
+func TestReadDirNamesLong_LinkTargets(t *testing.T) {
+	root := makeRoot(t)
+
+	lines, err := ReadDirNamesLong(joinPath(root, "usr/bin"), Flags{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, line := range lines {
+		if strings.Contains(line, "python"+reset+" -> ") {
+			found = true
+			if !strings.HasSuffix(line, " -> "+exeColour+"/usr/bin/python3"+reset) {
+				t.Errorf("link line %q should show the target coloured as an executable", line)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no link line in %q", lines)
+	}
+
+	lines, _ = ReadDirNamesLong(root, Flags{Root: root})
+	for _, line := range lines {
+		if strings.Contains(line, "dangling") && !strings.HasSuffix(line, " -> /missing") {
+			t.Errorf("broken link line %q should show the target uncoloured", line)
+		}
+	}
+}
+
+func TestReadDirNames_Dereference(t *testing.T) {
+	root := makeRoot(t)
+
+	names, err := ReadDirNames(root, Flags{Root: root, Dereference: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"etcdir":   dirColour,
+		"shell":    exeColour,
+		"dangling": symlinkColour,
+	}
+	for _, name := range names {
+		for file, colour := range want {
+			if strings.HasSuffix(name, file+reset) && name != colour+file+reset {
+				t.Errorf("-L listed %q, want colour %q for %v", name, colour, file)
+			}
+		}
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	root := makeRoot(t)
+	os.Mkdir(joinPath(root, "lib/x"), 0o755)
+
+	tests := []struct {
+		path, root, want string
+	}{
+		{path: joinPath(root, "usr/lib/x"), root: root, want: absPath(joinPath(root, "lib/x"))},
+		{path: joinPath(root, "usr/bin"), root: root, want: joinPath(root, "usr/bin")},
+		{path: joinPath(root, "usr/lib/x"), root: "", want: joinPath(root, "usr/lib/x")},
+	}
+	for _, tt := range tests {
+		if got := HostPath(tt.path, tt.root); got != tt.want {
+			t.Errorf("HostPath(%q, %q) = %q, want %q", tt.path, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestCollectDirectoriesRecursively_Dereference(t *testing.T) {
+	root := makeRoot(t)
+
+	dirs, err := CollectDirectoriesRecursively([]string{joinPath(root, "usr")}, Flags{Root: root, Dereference: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The link keeps its own path, which reads as the root's lib
+	lib := joinPath(root, "usr/lib")
+	found := false
+	for _, dir := range dirs {
+		if dir == lib {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("usr/lib should be listed under its own path, got %v", dirs)
+	}
+	if got := HostPath(lib, root); got != absPath(joinPath(root, "lib")) {
+		t.Errorf("HostPath(usr/lib) = %q, want the root's lib", got)
+	}
+}
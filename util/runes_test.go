@@ -0,0 +1,26 @@
+package util
+
+import "testing"
+
+func TestDecodeRune(t *testing.T) {
+	tests := []struct {
+		s    string
+		r    rune
+		size int
+	}{
+		{s: "", r: '�', size: 0},
+		{s: "abc", r: 'a', size: 1},
+		{s: "é!", r: 'é', size: 2},
+		{s: "日本", r: '日', size: 3},
+		{s: "\U0001f600", r: '\U0001f600', size: 4},
+		{s: "�x", r: '�', size: 3},
+		{s: "\xffx", r: '�', size: 1},
+		{s: "\xe6\x97", r: '�', size: 1},
+		{s: "\xed\xa0\x80", r: '�', size: 1}, // surrogate
+	}
+	for _, tt := range tests {
+		if r, size := DecodeRune(tt.s); r != tt.r || size != tt.size {
+			t.Errorf("DecodeRune(%q) = %q, %d, want %q, %d", tt.s, r, size, tt.r, tt.size)
+		}
+	}
+}
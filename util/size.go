@@ -0,0 +1,175 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scale says how a byte count is displayed: in whole units, or human-readable
+type Scale struct {
+	Unit      int64  // bytes per displayed unit; 0 means the column's default
+	Human     int64  // 1024 for -h, 1000 for --si; takes precedence over Unit
+	Suffix    string // printed after scaled values, e.g. "K" for --block-size=K
+	Thousands bool   // group digits in threes with commas
+}
+
+// HumanReadable and SI are the scales for -h and --si
+var (
+	HumanReadable = Scale{Human: 1024}
+	SI            = Scale{Human: 1000}
+)
+
+// blockSuffixes are the --block-size unit letters, in increasing powers
+const blockSuffixes = "KMGTPEZY"
+
+// ParseBlockSize parses a --block-size argument the way GNU ls does: an
+// optional "'" for thousands separators, an optional number and an
+// optional unit such as K (1024), KB (1000) or KiB (1024). When no number
+// is given the unit is printed after each value
+func ParseBlockSize(s string) (Scale, error) {
+	switch s {
+	case "human-readable":
+		return HumanReadable, nil
+	case "si":
+		return SI, nil
+	}
+
+	var scale Scale
+	rest := s
+	if strings.HasPrefix(rest, "'") {
+		scale.Thousands = true
+		rest = rest[1:]
+	}
+
+	i := 0
+	for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+		i++
+	}
+	number, suffix := rest[:i], rest[i:]
+
+	multiplier := int64(1)
+	if suffix != "" {
+		power := strings.IndexByte(blockSuffixes, upper(suffix[0]))
+		if power < 0 {
+			return Scale{}, fmt.Errorf("invalid block size '%v'", s)
+		}
+		base := int64(1024)
+		switch suffix[1:] {
+		case "", "iB":
+		case "B":
+			base = 1000
+		default:
+			return Scale{}, fmt.Errorf("invalid block size '%v'", s)
+		}
+		for p := 0; p <= power; p++ {
+			if multiplier > (1<<62)/base {
+				return Scale{}, fmt.Errorf("block size '%v' is too large", s)
+			}
+			multiplier *= base
+		}
+	}
+
+	count := int64(1)
+	if number != "" {
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || n == 0 {
+			return Scale{}, fmt.Errorf("invalid block size '%v'", s)
+		}
+		count = n
+	} else if suffix == "" {
+		return Scale{}, fmt.Errorf("invalid block size '%v'", s)
+	} else {
+		scale.Suffix = suffix
+	}
+
+	if count > (1<<63-1)/multiplier {
+		return Scale{}, fmt.Errorf("block size '%v' is too large", s)
+	}
+	scale.Unit = count * multiplier
+	return scale, nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// Format renders n bytes in this scale, using defaultUnit when no unit was
+// chosen. Like GNU coreutils, values are always rounded up
+func (s Scale) Format(n int64, defaultUnit int64) string {
+	if s.Human != 0 {
+		return humanSize(n, s.Human)
+	}
+	unit := s.Unit
+	if unit == 0 {
+		unit = defaultUnit
+	}
+	// n/unit rounded up, without the overflow of (n+unit-1)/unit
+	blocks := n / unit
+	if n%unit != 0 {
+		blocks++
+	}
+	str := strconv.FormatInt(blocks, 10)
+	if s.Thousands {
+		str = groupThousands(str)
+	}
+	return str + s.Suffix
+}
+
+// humanSize renders n with one of the K, M, G... suffixes in powers of base.
+// Values under 10 keep one decimal place, and everything rounds up, so
+// 1025 bytes is 1.1K and 10241 bytes is 11K
+func humanSize(n int64, base int64) string {
+	if n < base {
+		return strconv.FormatInt(n, 10)
+	}
+	units := blockSuffixes
+	if base == 1000 {
+		units = "kMGTPEZY"
+	}
+
+	u, b := uint64(n), uint64(base)
+	d, power := b, 0
+	for u/d >= b && power < len(units)-1 {
+		d *= b
+		power++
+	}
+	q, r := u/d, u%d
+
+	if q < 10 {
+		tenths := q*10 + (r*10+d-1)/d
+		if tenths < 100 {
+			return fmt.Sprintf("%d.%d%c", tenths/10, tenths%10, units[power])
+		}
+	}
+	whole := q
+	if r > 0 {
+		whole++
+	}
+	if whole >= b && power < len(units)-1 {
+		return fmt.Sprintf("1.0%c", units[power+1])
+	}
+	return fmt.Sprintf("%d%c", whole, units[power])
+}
+
+// groupThousands inserts commas between groups of three digits
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		n    int64
+		base int64
+		want string
+	}{
+		{0, 1024, "0"},
+		{1023, 1024, "1023"},
+		{1024, 1024, "1.0K"},
+		{1025, 1024, "1.1K"},
+		{4096, 1024, "4.0K"},
+		{1363149, 1024, "1.4M"},
+		{1363148, 1024, "1.3M"},
+		{10239, 1024, "10K"},
+		{10241, 1024, "11K"},
+		{1048575, 1024, "1.0M"},
+		{1 << 30, 1024, "1.0G"},
+		{999, 1000, "999"},
+		{1000, 1000, "1.0k"},
+		{4096, 1000, "4.1k"},
+		{1500000, 1000, "1.5M"},
+		{1<<63 - 1, 1024, "8.0E"},
+	}
+
+	for _, tt := range tests {
+		if got := humanSize(tt.n, tt.base); got != tt.want {
+			t.Errorf("humanSize(%d, %d) = %q, want %q", tt.n, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlockSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Scale
+	}{
+		{"K", Scale{Unit: 1024, Suffix: "K"}},
+		{"1K", Scale{Unit: 1024}},
+		{"M", Scale{Unit: 1 << 20, Suffix: "M"}},
+		{"1MiB", Scale{Unit: 1 << 20}},
+		{"MB", Scale{Unit: 1000000, Suffix: "MB"}},
+		{"kB", Scale{Unit: 1000, Suffix: "kB"}},
+		{"512", Scale{Unit: 512}},
+		{"'1", Scale{Unit: 1, Thousands: true}},
+		{"human-readable", HumanReadable},
+		{"si", SI},
+		{"E", Scale{Unit: 1 << 60, Suffix: "E"}},
+		{"7E", Scale{Unit: 7 << 60}},
+		{"9223372036854775807", Scale{Unit: 1<<63 - 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBlockSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseBlockSize(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBlockSize(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "0", "Q", "1KQ", "'", "YB", "8E", "9223372036854775808", "10000000000000000000K"} {
+		if _, err := ParseBlockSize(bad); err == nil {
+			t.Errorf("ParseBlockSize(%q) expected an error", bad)
+		}
+	}
+}
+
+func TestScaleFormat(t *testing.T) {
+	tests := []struct {
+		scale       Scale
+		n           int64
+		defaultUnit int64
+		want        string
+	}{
+		{Scale{}, 20000, 1, "20000"},
+		{Scale{}, 28672, 1024, "28"},
+		{Scale{}, 28673, 1024, "29"},
+		{Scale{Unit: 1024, Suffix: "K"}, 1, 1, "1K"},
+		{Scale{Unit: 1024, Suffix: "K"}, 0, 1, "0K"},
+		{Scale{Unit: 1, Thousands: true}, 1234567, 1, "1,234,567"},
+		{Scale{Unit: 1, Thousands: true}, 123456, 1, "123,456"},
+		{HumanReadable, 4096, 1, "4.0K"},
+		{Scale{Unit: 7 << 60}, 5, 1, "1"},
+		{Scale{Unit: 1<<63 - 1}, 5, 1, "1"},
+		{Scale{Unit: 1<<63 - 1}, 1<<63 - 1, 1, "1"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.scale.Format(tt.n, tt.defaultUnit); got != tt.want {
+			t.Errorf("%+v.Format(%d, %d) = %q, want %q", tt.scale, tt.n, tt.defaultUnit, got, tt.want)
+		}
+	}
+}
+
+func TestReadDirNamesLong_HumanReadable(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(joinPath(base, "big.bin"), make([]byte, 3<<20), 0o644)
+
+	lines, err := ReadDirNamesLong(base, Flags{FileSize: HumanReadable, BlockSize: HumanReadable})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", lines)
+	}
+	if !strings.HasPrefix(lines[0], "total ") || !strings.HasSuffix(lines[0], "M") {
+		t.Errorf("expected a human-readable total, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], " 3.0M ") {
+		t.Errorf("expected size 3.0M, got %q", lines[1])
+	}
+}
@@ -14,8 +14,9 @@ import (
 // - Alphabetic vs. alphabetic: lowercase before uppercase, case-insensitive otherwise.
 // - Other pairs (special vs. special, alphabetic vs. special): ASCII order.
 func CompareStrings(a, b string) bool {
-	// Convert strings to runes for proper handling
-	ra, rb := []rune(a), []rune(b)
+	// Convert strings to runes for proper handling, keeping bytes that are
+	// not UTF-8 apart
+	ra, rb := sortRunes(a), sortRunes(b)
 
 	// Check if both strings contain only special (non-alphabetic, non-numeric) characters
 	hasSignificantA, hasSignificantB := false, false
@@ -120,6 +121,41 @@ func CompareStrings(a, b string) bool {
 	return len(ra) < len(rb)
 }
 
+// invalidByteBase puts a byte that is not part of a UTF-8 character past
+// every real character, in byte order, when comparing names
+const invalidByteBase = '\U0010ffff' + 1
+
+// sortRunes is []rune(s), except that each byte that is not part of a
+// UTF-8 character gets a distinct value instead of all becoming U+FFFD
+func sortRunes(s string) []rune {
+	runes := make([]rune, 0, len(s))
+	for i, r := range s {
+		if r == runeError && !strings.HasPrefix(s[i:], "\ufffd") {
+			r = invalidByteBase + rune(s[i])
+		}
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// LessName orders file names for listing: . and .. first, then by
+// CompareStrings ignoring leading dots
+func LessName(a, b string) bool {
+	rank := func(name string) int {
+		switch name {
+		case ".":
+			return 0
+		case "..":
+			return 1
+		}
+		return 2
+	}
+	if ra, rb := rank(a), rank(b); ra != rb || ra < 2 {
+		return ra < rb
+	}
+	return CompareStrings(TrimStart(a), TrimStart(b))
+}
+
 func InsertSorted(name, colour, reset string, names []string) []string {
 	if name == "." || name == ".." {
 		return append([]string{fmt.Sprintf("%s%s%s", colour, name, reset)}, names...)
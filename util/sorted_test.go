@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -395,3 +396,39 @@ func TestInsertSortedLongByTime_ErrorHandling(t *testing.T) {
 		t.Errorf("InsertSortedLongByTime() with non-existent file = %v, want %v", result, expected)
 	}
 }
+
+func TestLessName(t *testing.T) {
+	names := []string{"b.txt", "..", ".hidden", "A.txt", ".", "a.txt"}
+	sort.SliceStable(names, func(i, j int) bool { return LessName(names[i], names[j]) })
+
+	want := []string{".", "..", "a.txt", "A.txt", "b.txt", ".hidden"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted names = %v, want %v", names, want)
+	}
+}
+
+func TestCompareStrings_InvalidUTF8(t *testing.T) {
+	// Bytes that are not UTF-8 sort by value after every real character,
+	// rather than all comparing equal as U+FFFD
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "\xfe", b: "\xff", want: true},
+		{a: "\xff", b: "\xfe", want: false},
+		{a: "-\xff", b: "-\xef\xbf\xbd", want: false},
+		{a: "-\xef\xbf\xbd", b: "-\xff", want: true},
+		{a: "x\x80", b: "x\x81", want: true},
+	}
+	for _, tt := range tests {
+		if got := CompareStrings(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareStrings(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	names := []string{"b\xff", "a", "b\xfe", "b\xef\xbf\xbd"}
+	sort.SliceStable(names, func(i, j int) bool { return LessName(names[i], names[j]) })
+	if want := []string{"a", "b\xef\xbf\xbd", "b\xfe", "b\xff"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,16 @@
+package util
+
+import (
+	"syscall"
+	"time"
+)
+
+// AccessTime returns when the file behind st was last read
+func AccessTime(st *syscall.Stat_t) time.Time {
+	return time.Unix(st.Atim.Unix())
+}
+
+// ChangeTime returns when the inode behind st last changed
+func ChangeTime(st *syscall.Stat_t) time.Time {
+	return time.Unix(st.Ctim.Unix())
+}
@@ -0,0 +1,3 @@
+package util
+
+const sysStatx = 291
@@ -0,0 +1,235 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// Resolver maps numeric user and group ids to names and back
+type Resolver interface {
+	UserName(uid uint32) (string, bool)
+	GroupName(gid uint32) (string, bool)
+	UserID(name string) (uint32, bool)
+	GroupID(name string) (uint32, bool)
+}
+
+// NSSResolver asks the system user database through os/user, which may go
+// through NSS and so be slow for large or remote directories
+type NSSResolver struct{}
+
+func (NSSResolver) UserName(uid uint32) (string, bool) {
+	u, err := user.LookupId(fmt.Sprint(uid))
+	if err != nil {
+		return "", false
+	}
+	return u.Username, true
+}
+
+func (NSSResolver) GroupName(gid uint32) (string, bool) {
+	g, err := user.LookupGroupId(fmt.Sprint(gid))
+	if err != nil {
+		return "", false
+	}
+	return g.Name, true
+}
+
+func (NSSResolver) UserID(name string) (uint32, bool) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, false
+	}
+	return parseID(u.Uid)
+}
+
+func (NSSResolver) GroupID(name string) (uint32, bool) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, false
+	}
+	return parseID(g.Gid)
+}
+
+// FileResolver reads etc/passwd and etc/group below Root itself, with no
+// cgo or NSS involved, so it works in static builds and minimal containers
+type FileResolver struct {
+	Root string
+
+	loaded bool
+	users  idTable
+	groups idTable
+}
+
+// idTable is one parsed passwd or group file
+type idTable struct {
+	names map[uint32]string
+	ids   map[string]uint32
+}
+
+// NewFileResolver returns a resolver for the passwd and group files under root
+func NewFileResolver(root string) *FileResolver {
+	return &FileResolver{Root: root}
+}
+
+// load reads both files the first time a name or id is asked for
+func (r *FileResolver) load() {
+	if r.loaded {
+		return
+	}
+	r.users = readIDFile(joinPath(r.Root, "etc/passwd"))
+	r.groups = readIDFile(joinPath(r.Root, "etc/group"))
+	r.loaded = true
+}
+
+func (r *FileResolver) UserName(uid uint32) (string, bool) {
+	r.load()
+	name, ok := r.users.names[uid]
+	return name, ok
+}
+
+func (r *FileResolver) GroupName(gid uint32) (string, bool) {
+	r.load()
+	name, ok := r.groups.names[gid]
+	return name, ok
+}
+
+func (r *FileResolver) UserID(name string) (uint32, bool) {
+	r.load()
+	id, ok := r.users.ids[name]
+	return id, ok
+}
+
+func (r *FileResolver) GroupID(name string) (uint32, bool) {
+	r.load()
+	id, ok := r.groups.ids[name]
+	return id, ok
+}
+
+// readIDFile parses a passwd or group file, both of which keep the name in
+// the first field and the id in the third. The first entry for an id or a
+// name wins, as with getpwuid. A missing file gives an empty table
+func readIDFile(path string) idTable {
+	table := idTable{names: map[uint32]string{}, ids: map[string]uint32{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return table
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || line[0] == '#' || line[0] == '+' || line[0] == '-' {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		id, ok := parseID(fields[2])
+		if !ok {
+			continue
+		}
+		if _, seen := table.names[id]; !seen {
+			table.names[id] = fields[0]
+		}
+		if _, seen := table.ids[fields[0]]; !seen {
+			table.ids[fields[0]] = id
+		}
+	}
+	return table
+}
+
+func parseID(s string) (uint32, bool) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err == nil
+}
+
+// cachedResolver remembers every answer, found or not, so each id is looked
+// up at most once per run no matter how many files share it
+type cachedResolver struct {
+	Resolver
+
+	userNames  map[uint32]cachedName
+	groupNames map[uint32]cachedName
+}
+
+type cachedName struct {
+	name string
+	ok   bool
+}
+
+// NewCachedResolver wraps r with a per-run id to name cache
+func NewCachedResolver(r Resolver) Resolver {
+	return &cachedResolver{
+		Resolver:   r,
+		userNames:  map[uint32]cachedName{},
+		groupNames: map[uint32]cachedName{},
+	}
+}
+
+func (c *cachedResolver) UserName(uid uint32) (string, bool) {
+	return c.lookup(c.userNames, uid, c.Resolver.UserName)
+}
+
+func (c *cachedResolver) GroupName(gid uint32) (string, bool) {
+	return c.lookup(c.groupNames, gid, c.Resolver.GroupName)
+}
+
+func (c *cachedResolver) lookup(cache map[uint32]cachedName, id uint32, resolve func(uint32) (string, bool)) (string, bool) {
+	if hit, ok := cache[id]; ok {
+		return hit.name, hit.ok
+	}
+	name, ok := resolve(id)
+	cache[id] = cachedName{name, ok}
+	return name, ok
+}
+
+// resolver is the id resolver for this run
+var resolver = NewCachedResolver(NSSResolver{})
+
+// SetResolver makes r, behind a fresh cache, the resolver used for names
+func SetResolver(r Resolver) {
+	resolver = NewCachedResolver(r)
+}
+
+// IDName is a user or group name given to --user or --group
+type IDName struct {
+	Group bool
+	Name  string
+}
+
+// CheckIDNames reports the first of names that the resolver in use does
+// not know. Numeric ids need no lookup
+func CheckIDNames(names []IDName) error {
+	for _, n := range names {
+		if _, ok := parseID(n.Name); ok {
+			continue
+		}
+		if n.Group {
+			if _, ok := resolver.GroupID(n.Name); !ok {
+				return fmt.Errorf("unknown group '%v'", n.Name)
+			}
+		} else if _, ok := resolver.UserID(n.Name); !ok {
+			return fmt.Errorf("unknown user '%v'", n.Name)
+		}
+	}
+	return nil
+}
+
+// UserName returns the login name for uid, or the number itself when it has none
+func UserName(uid uint32) string {
+	if name, ok := resolver.UserName(uid); ok {
+		return name
+	}
+	return fmt.Sprint(uid)
+}
+
+// GroupName returns the group name for gid, or the number itself when it has none
+func GroupName(gid uint32) string {
+	if name, ok := resolver.GroupName(gid); ok {
+		return name
+	}
+	return fmt.Sprint(gid)
+}
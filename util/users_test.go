@@ -0,0 +1,131 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func writeIDFiles(t *testing.T, passwd, group string) string {
+	t.Helper()
+	root := t.TempDir()
+	os.Mkdir(joinPath(root, "etc"), 0o755)
+	if err := os.WriteFile(joinPath(root, "etc/passwd"), []byte(passwd), 0o644); err != nil {
+		t.Fatalf("writing passwd: %v", err)
+	}
+	if err := os.WriteFile(joinPath(root, "etc/group"), []byte(group), 0o644); err != nil {
+		t.Fatalf("writing group: %v", err)
+	}
+	return root
+}
+
+func TestFileResolver(t *testing.T) {
+	root := writeIDFiles(t,
+		"# comment\nroot:x:0:0:root:/root:/bin/sh\nalice:x:1000:1000::/home/alice:/bin/sh\nbroken line\nalias:x:1000:1000::/:/bin/sh\n+nis::::::\n",
+		"root:x:0:\nstaff:x:50:alice,bob\n",
+	)
+	r := NewFileResolver(root)
+
+	if name, ok := r.UserName(1000); !ok || name != "alice" {
+		t.Errorf("UserName(1000) = %q, %v; want alice (first entry wins)", name, ok)
+	}
+	if _, ok := r.UserName(4242); ok {
+		t.Errorf("UserName(4242) should not resolve")
+	}
+	if name, ok := r.GroupName(50); !ok || name != "staff" {
+		t.Errorf("GroupName(50) = %q, %v; want staff", name, ok)
+	}
+	if id, ok := r.UserID("alias"); !ok || id != 1000 {
+		t.Errorf("UserID(alias) = %d, %v; want 1000", id, ok)
+	}
+	if id, ok := r.GroupID("root"); !ok || id != 0 {
+		t.Errorf("GroupID(root) = %d, %v; want 0", id, ok)
+	}
+	if _, ok := r.UserID("+nis"); ok {
+		t.Errorf("NIS compat lines should be skipped")
+	}
+}
+
+func TestFileResolver_MissingFiles(t *testing.T) {
+	r := NewFileResolver(t.TempDir())
+	if _, ok := r.UserName(0); ok {
+		t.Errorf("expected no names without a passwd file")
+	}
+}
+
+// countingResolver counts lookups made through it
+type countingResolver struct {
+	NSSResolver
+	calls int
+}
+
+func (c *countingResolver) UserName(uid uint32) (string, bool) {
+	c.calls++
+	return "user", uid == 1
+}
+
+func TestCachedResolver(t *testing.T) {
+	counter := &countingResolver{}
+	r := NewCachedResolver(counter)
+
+	for i := 0; i < 100; i++ {
+		r.UserName(1)
+		r.UserName(2)
+	}
+	if counter.calls != 2 {
+		t.Errorf("expected 2 lookups through the cache, got %d", counter.calls)
+	}
+	if _, ok := r.UserName(2); ok {
+		t.Errorf("a failed lookup should stay failed when cached")
+	}
+}
+
+func TestSetResolver(t *testing.T) {
+	old := resolver
+	t.Cleanup(func() { resolver = old })
+
+	SetResolver(NewFileResolver(writeIDFiles(t, "build:x:4000:4000::/:/bin/sh\n", "builders:x:4000:\n")))
+
+	if got := UserName(4000); got != "build" {
+		t.Errorf("UserName(4000) = %q, want build", got)
+	}
+	if got := GroupName(4000); got != "builders" {
+		t.Errorf("GroupName(4000) = %q, want builders", got)
+	}
+	if got := UserName(4001); got != "4001" {
+		t.Errorf("UserName(4001) = %q, want the number", got)
+	}
+
+	p, err := NewFilter("user", "build")
+	if err != nil {
+		t.Fatalf("NewFilter() error: %v", err)
+	}
+	base := t.TempDir()
+	info, _ := os.Lstat(base)
+	if os.Getuid() != 4000 && p(Entry{FileInfo: info, Path: base}) {
+		t.Errorf("--user=build should only match files owned by uid 4000")
+	}
+}
+
+func TestCheckIDNames(t *testing.T) {
+	old := resolver
+	t.Cleanup(func() { resolver = old })
+
+	SetResolver(NewFileResolver(writeIDFiles(t, "build:x:4000:4000::/:/bin/sh\n", "builders:x:4000:\n")))
+
+	tests := []struct {
+		names []IDName
+		want  string
+	}{
+		{names: nil},
+		{names: []IDName{{Name: "build"}, {Group: true, Name: "builders"}, {Name: "4242"}}},
+		{names: []IDName{{Name: "build"}, {Name: "nosuchuser"}}, want: "unknown user 'nosuchuser'"},
+		{names: []IDName{{Group: true, Name: "build"}}, want: "unknown group 'build'"},
+	}
+	for _, tt := range tests {
+		err := CheckIDNames(tt.names)
+		if got := fmt.Sprint(err); tt.want == "" && err != nil || tt.want != "" && got != tt.want {
+			t.Errorf("CheckIDNames(%v) = %v, want %q", tt.names, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// DisplayWidth returns how many terminal cells s takes, ignoring colour
+// escapes. It works a grapheme cluster at a time, so combining marks and
+// the parts of a ZWJ emoji sequence add nothing, and counts East Asian
+// wide and fullwidth characters and emoji as two cells
+func DisplayWidth(s string) int {
+	s = StripANSI(s)
+	width := 0
+	for s != "" {
+		var cluster string
+		cluster, s = nextGrapheme(s)
+		width += clusterWidth(cluster)
+	}
+	return width
+}
+
+// spaces returns n spaces, or none when n is negative
+func spaces(n int) string {
+	return strings.Repeat(" ", max(n, 0))
+}
+
+const (
+	zwj            = '\u200d'
+	textStyle      = '\ufe0e' // VS15
+	emojiStyle     = '\ufe0f' // VS16
+	regionalFirst  = '\U0001f1e6'
+	regionalLast   = '\U0001f1ff'
+	modifierFirst  = '\U0001f3fb' // skin tones
+	modifierLast   = '\U0001f3ff'
+	hangulMedial   = '\u1160' // jamo vowels and trailing consonants
+	hangulMedialTo = '\u11ff'
+)
+
+// nextGrapheme splits the first grapheme cluster off s, following the
+// parts of Unicode's rules that matter for width: combining marks,
+// variation selectors, emoji modifiers and tags extend a cluster, a ZWJ
+// joins the next character to it, regional indicators pair up into flags
+// and Hangul jamo join into syllables
+func nextGrapheme(s string) (cluster, rest string) {
+	r, size := DecodeRune(s)
+	end := size
+	if r == '\r' && end < len(s) && s[end] == '\n' {
+		return s[:end+1], s[end+1:]
+	}
+	if isRegional(r) {
+		if next, n := DecodeRune(s[end:]); isRegional(next) {
+			end += n
+		}
+	}
+
+	prev := r
+	for end < len(s) {
+		next, n := DecodeRune(s[end:])
+		switch {
+		case prev == zwj && !isControl(next):
+		case isExtender(next):
+		case isHangulMedial(next) && isHangul(prev):
+		default:
+			return s[:end], s[end:]
+		}
+		prev = next
+		end += n
+	}
+	return s, ""
+}
+
+// clusterWidth is the width of one grapheme cluster: that of its first
+// character, made two cells by an emoji-style variation selector and one
+// by a text-style one
+func clusterWidth(cluster string) int {
+	r, size := DecodeRune(cluster)
+	if isRegional(r) && len(cluster) > size {
+		return 2
+	}
+	width := runeWidth(r)
+	for _, c := range cluster[size:] {
+		switch c {
+		case emojiStyle:
+			if width == 1 {
+				width = 2
+			}
+		case textStyle:
+			if width == 2 && isEmoji(r) {
+				width = 1
+			}
+		}
+	}
+	return width
+}
+
+// runeWidth is the width of r on its own: none for controls, combining
+// marks and format characters, two for wide characters and one otherwise
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || isControl(r):
+		return 0
+	case r < 0x300:
+		return 1
+	case isExtender(r) || r == zwj || isFormat(r) || isHangulMedial(r):
+		return 0
+	case inTable(r, wideRanges):
+		return 2
+	}
+	return 1
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r >= 0x7f && r < 0xa0
+}
+
+// isExtender reports whether r extends the cluster before it
+func isExtender(r rune) bool {
+	return isMark(r) ||
+		r == zwj ||
+		r >= 0xfe00 && r <= 0xfe0f ||
+		r >= 0xe0100 && r <= 0xe01ef ||
+		r >= modifierFirst && r <= modifierLast ||
+		r >= 0xe0020 && r <= 0xe007f // tags, as in subdivision flags
+}
+
+func isRegional(r rune) bool {
+	return r >= regionalFirst && r <= regionalLast
+}
+
+func isHangul(r rune) bool {
+	return r >= 0x1100 && r <= 0x11ff || r >= 0xac00 && r <= 0xd7a3
+}
+
+func isHangulMedial(r rune) bool {
+	return r >= hangulMedial && r <= hangulMedialTo || r >= 0xd7b0 && r <= 0xd7ff
+}
+
+// isEmoji reports whether r is one of the wide characters in the emoji
+// blocks, which a text-style selector narrows
+func isEmoji(r rune) bool {
+	return r >= 0x1f000 && r <= 0x1faff || r >= 0x2600 && r <= 0x27bf || r >= 0x2300 && r <= 0x23ff
+}
+
+func inTable(r rune, table [][2]rune) bool {
+	i := sort.Search(len(table), func(i int) bool { return table[i][1] >= r })
+	return i < len(table) && table[i][0] <= r
+}
+
+// wideRanges are the East Asian Wide and Fullwidth characters, emoji with
+// default emoji presentation among them, from Unicode 15's
+// EastAsianWidth.txt
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x17000, 0x18cff}, {0x1aff0, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f202}, {0x1f210, 0x1f23b},
+	{0x1f240, 0x1f248}, {0x1f250, 0x1f251}, {0x1f260, 0x1f265}, {0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7}, {0x1f6dc, 0x1f6df}, {0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc},
+	{0x1f7e0, 0x1f7eb}, {0x1f7f0, 0x1f7f0}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
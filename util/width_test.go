@@ -0,0 +1,69 @@
+package util
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{name: "empty", s: "", want: 0},
+		{name: "ascii", s: "file.txt", want: 8},
+		{name: "colour escapes", s: "\033[01;34mdir\033[0m", want: 3},
+		{name: "nerd font icon", s: "\uf115 src", want: 5},
+		{name: "latin accents", s: "café.txt", want: 8},
+		{name: "combining acute", s: "cafe\u0301.txt", want: 8},
+		{name: "stacked combining marks", s: "a\u0300\u0301\u0302b", want: 2},
+		{name: "CJK", s: "日本語.txt", want: 10},
+		{name: "hangul syllables", s: "한국어", want: 6},
+		{name: "hangul jamo", s: "ᄀ\u1161\u11a8", want: 2},
+		{name: "fullwidth", s: "ＡＢＣ", want: 6},
+		{name: "halfwidth katakana", s: "ｶﾀｶﾅ", want: 4},
+		{name: "emoji", s: "\U0001f600.png", want: 6},
+		{name: "ZWJ family", s: "\U0001f468\u200d\U0001f469\u200d\U0001f467\u200d\U0001f466", want: 2},
+		{name: "ZWJ with skin tone", s: "\U0001f469\U0001f3fd\u200d\U0001f4bb", want: 2},
+		{name: "flag", s: "\U0001f1ef\U0001f1f5", want: 2},
+		{name: "two flags", s: "\U0001f1ef\U0001f1f5\U0001f1f0\U0001f1ea", want: 4},
+		{name: "emoji presentation selector", s: "❤\ufe0f", want: 2},
+		{name: "text presentation selector", s: "⌚\ufe0e", want: 1},
+		{name: "zero width space", s: "a\u200bb", want: 2},
+		{name: "control characters", s: "a\tb\x1b", want: 2},
+	}
+	for _, tt := range tests {
+		if got := DisplayWidth(tt.s); got != tt.want {
+			t.Errorf("%v: DisplayWidth(%q) = %d, want %d", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNextGrapheme(t *testing.T) {
+	s := "e\u0301\U0001f468\u200d\U0001f469x\r\n"
+	var clusters []string
+	for s != "" {
+		var c string
+		c, s = nextGrapheme(s)
+		clusters = append(clusters, c)
+	}
+	want := []string{"e\u0301", "\U0001f468\u200d\U0001f469", "x", "\r\n"}
+	if len(clusters) != len(want) {
+		t.Fatalf("clusters = %q, want %q", clusters, want)
+	}
+	for i := range want {
+		if clusters[i] != want[i] {
+			t.Errorf("cluster %d = %q, want %q", i, clusters[i], want[i])
+		}
+	}
+}
+
+func TestPad_Wide(t *testing.T) {
+	if got := pad("用户", 6, true); got != "用户  " {
+		t.Errorf("pad(left) = %q, want two spaces of padding", got)
+	}
+	if got := pad("e\u0301", 3, false); got != "  e\u0301" {
+		t.Errorf("pad(right) = %q, want two spaces of padding", got)
+	}
+	if got := pad("\033[01;34m用户\033[0m", 5, true); got != "\033[01;34m用户\033[0m " {
+		t.Errorf("pad(coloured) = %q, want one space of padding", got)
+	}
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// xattrIndicator returns the mark that follows the mode of a file with
+// the named extended attributes: '+' for a POSIX ACL, as GNU ls shows it,
+// '@' for any other attributes, the mark BSD ls uses, and nothing
+// otherwise. SELinux labels, which every file on such a system has, do not
+// count. Attributes are only read on Linux, so elsewhere there is no mark
+func xattrIndicator(names []string) string {
+	mark := ""
+	for _, name := range names {
+		switch name {
+		case "system.posix_acl_access", "system.posix_acl_default":
+			return "+"
+		case "security.selinux":
+		default:
+			mark = "@"
+		}
+	}
+	return mark
+}
+
+// xattrValue shows an attribute value as text when it is printable UTF-8,
+// with a NUL terminator dropped, and with each other byte as \xNN otherwise
+func xattrValue(value []byte) string {
+	text := strings.TrimSuffix(string(value), "\x00")
+	if !hasUnprintable(text) {
+		return text
+	}
+	var b strings.Builder
+	for _, c := range value {
+		if c >= 0x20 && c < 0x7f && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
+
+// xattrLines returns the extended attributes named in names, one to a
+// line as "name: value", indented to sit under an entry
+func xattrLines(path string, names []string, follow bool) []string {
+	var lines []string
+	for _, name := range names {
+		value, err := GetXattr(path, name, follow)
+		if err != nil {
+			lines = append(lines, "    "+name+": ?")
+			continue
+		}
+		lines = append(lines, "    "+name+": "+xattrValue(value))
+	}
+	return lines
+}
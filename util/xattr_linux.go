@@ -0,0 +1,83 @@
+package util
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// HasCapability reports whether the file at path carries file capabilities,
+// which Linux keeps in the security.capability extended attribute
+func HasCapability(path string) bool {
+	n, err := syscall.Getxattr(path, "security.capability", nil)
+	return err == nil && n > 0
+}
+
+// ListXattrs returns the names of the extended attributes of the file at
+// path, or none when they cannot be read. A symlink's own are listed
+// unless follow asks for its target's
+func ListXattrs(path string, follow bool) []string {
+	trap := uintptr(syscall.SYS_LLISTXATTR)
+	if follow {
+		trap = syscall.SYS_LISTXATTR
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil
+	}
+	var buf []byte
+	for {
+		// Ask for the size, then read into a buffer that big, trying again
+		// should the list grow in between
+		n, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(p)), 0, 0)
+		if errno != 0 || n == 0 {
+			return nil
+		}
+		buf = make([]byte, n)
+		n, _, errno = syscall.Syscall(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&buf[0])), n)
+		if errno == syscall.ERANGE {
+			continue
+		}
+		if errno != 0 {
+			return nil
+		}
+		buf = buf[:n]
+		break
+	}
+	return strings.Split(strings.TrimSuffix(string(buf), "\x00"), "\x00")
+}
+
+// GetXattr returns the value of the extended attribute name of the file at
+// path, or with follow of the file a symlink there points to
+func GetXattr(path, name string, follow bool) ([]byte, error) {
+	trap := uintptr(syscall.SYS_LGETXATTR)
+	if follow {
+		trap = syscall.SYS_GETXATTR
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		n, _, errno := syscall.Syscall6(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), 0, 0, 0, 0)
+		if errno != 0 {
+			return nil, errno
+		}
+		if n == 0 {
+			return []byte{}, nil
+		}
+		buf := make([]byte, n)
+		n, _, errno = syscall.Syscall6(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), uintptr(unsafe.Pointer(&buf[0])), n, 0, 0)
+		if errno == syscall.ERANGE {
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+		return buf[:n], nil
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestReadDirNamesLong_Xattrs(t *testing.T) {
+	base := t.TempDir()
+	path := joinPath(base, "tagged")
+	os.WriteFile(path, nil, 0o644)
+	os.WriteFile(joinPath(base, "plain"), nil, 0o644)
+	if err := syscall.Setxattr(path, "user.comment", []byte("hello"), 0); err != nil {
+		t.Skipf("file system does not take user attributes: %v", err)
+	}
+
+	if got := ListXattrs(path, false); !reflect.DeepEqual(got, []string{"user.comment"}) {
+		t.Errorf("ListXattrs() = %q, want user.comment", got)
+	}
+	if got, err := GetXattr(path, "user.comment", false); err != nil || string(got) != "hello" {
+		t.Errorf("GetXattr() = %q, %v; want hello", got, err)
+	}
+
+	lines, err := ReadDirNamesLong(base, Flags{Columns: []string{"perms", "name"}, Xattrs: true, NoColor: true})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	want := []string{
+		"-rw-r--r--  plain",
+		"-rw-r--r--@ tagged",
+		"    user.comment: hello",
+	}
+	if !reflect.DeepEqual(lines[1:], want) {
+		t.Errorf("lines = %q, want %q", lines[1:], want)
+	}
+}
+
+func TestReadDirNamesLong_XattrsDereference(t *testing.T) {
+	base := t.TempDir()
+	target := joinPath(t.TempDir(), "tagged")
+	os.WriteFile(target, nil, 0o644)
+	if err := syscall.Setxattr(target, "user.comment", []byte("hello"), 0); err != nil {
+		t.Skipf("file system does not take user attributes: %v", err)
+	}
+	link := joinPath(base, "link")
+	os.Symlink(target, link)
+
+	if got := ListXattrs(link, false); len(got) != 0 {
+		t.Errorf("ListXattrs(link, false) = %q, want the link's own, none", got)
+	}
+	if got, err := GetXattr(link, "user.comment", true); err != nil || string(got) != "hello" {
+		t.Errorf("GetXattr(link, true) = %q, %v; want the target's hello", got, err)
+	}
+
+	// -L shows the target, attributes and all
+	lines, err := ReadDirNamesLong(base, Flags{Columns: []string{"perms", "xattr", "name"}, Xattrs: true, Dereference: true, NoColor: true})
+	if err != nil {
+		t.Fatalf("ReadDirNamesLong() error: %v", err)
+	}
+	want := []string{
+		"-rw-r--r--@ 1 link",
+		"    user.comment: hello",
+	}
+	if !reflect.DeepEqual(lines[1:], want) {
+		t.Errorf("lines = %q, want %q", lines[1:], want)
+	}
+}
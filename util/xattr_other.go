@@ -0,0 +1,25 @@
+//go:build !linux
+
+package util
+
+import "syscall"
+
+// HasCapability reports whether the file at path carries file capabilities,
+// which only Linux has
+func HasCapability(path string) bool {
+	return false
+}
+
+// ListXattrs returns the names of the extended attributes of the file at
+// path, which are only read on Linux. macOS and FreeBSD have their own
+// calls for them, not reached through package syscall, so none are listed
+// there and no '@' mark or --xattrs lines appear
+func ListXattrs(path string, follow bool) []string {
+	return nil
+}
+
+// GetXattr returns the value of the extended attribute name of the file at
+// path, which are only read on Linux
+func GetXattr(path, name string, follow bool) ([]byte, error) {
+	return nil, syscall.ENOTSUP
+}
@@ -0,0 +1,39 @@
+package util
+
+import "testing"
+
+func TestXattrIndicator(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{names: nil, want: ""},
+		{names: []string{"security.selinux"}, want: ""},
+		{names: []string{"user.comment"}, want: "@"},
+		{names: []string{"user.comment", "system.posix_acl_access"}, want: "+"},
+		{names: []string{"system.posix_acl_default"}, want: "+"},
+	}
+	for _, tt := range tests {
+		if got := xattrIndicator(tt.names); got != tt.want {
+			t.Errorf("xattrIndicator(%q) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestXattrValue(t *testing.T) {
+	tests := []struct {
+		value []byte
+		want  string
+	}{
+		{value: []byte("hello"), want: "hello"},
+		{value: []byte("unconfined_u:object_r:user_home_t:s0\x00"), want: "unconfined_u:object_r:user_home_t:s0"},
+		{value: []byte("café"), want: "café"},
+		{value: []byte{0x00, 0xff, 'a', '\\'}, want: `\x00\xffa\x5c`},
+		{value: []byte{}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := xattrValue(tt.value); got != tt.want {
+			t.Errorf("xattrValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}